@@ -0,0 +1,4 @@
+data "galaxy_dataset" "reference" {
+  history_id = "f2db41e1fa331b3e"
+  name = "hg38.fa"
+}
\ No newline at end of file
@@ -0,0 +1,6 @@
+data "galaxy_history_contents" "example" {
+  history_id = "f2db41e1fa331b3e"
+  type = "dataset"
+  state = "ok"
+  extension = "fasta"
+}
\ No newline at end of file
@@ -0,0 +1,15 @@
+provider "galaxy" {
+  alias = "staging"
+  host  = "https://staging.example.org"
+}
+
+data "galaxy_stored_workflow" "example" {
+  provider = galaxy.staging
+  owner    = "lab"
+  slug     = "assembly"
+}
+
+resource "galaxy_stored_workflow" "example" {
+  json      = data.galaxy_stored_workflow.example.json
+  published = true
+}
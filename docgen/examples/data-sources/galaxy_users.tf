@@ -0,0 +1,15 @@
+variable "lab_members" {
+  type = list(string)
+  default = ["alice@example.com", "bob@example.com"]
+}
+
+data "galaxy_users" "lab" {
+  search = "@example.com"
+}
+
+resource "galaxy_quota" "lab" {
+  name = "lab"
+  description = "Lab quota"
+  amount = "1T"
+  users = [for email in var.lab_members : data.galaxy_users.lab.ids_by_email[email]]
+}
@@ -0,0 +1,17 @@
+data "galaxy_workflow_repositories" "example" {
+  json            = file("workflow.ga")
+  check_installed = true
+}
+
+resource "galaxy_repository" "example" {
+  for_each           = data.galaxy_workflow_repositories.example.repositories
+  tool_shed          = each.value.tool_shed
+  owner              = each.value.owner
+  name               = each.value.name
+  changeset_revision = each.value.changeset_revision
+  remove_from_disk   = true
+}
+
+output "missing_tools" {
+  value = flatten([for repo in data.galaxy_workflow_repositories.example.missing_repositories : repo.tools[*].tool_id])
+}
@@ -0,0 +1,28 @@
+resource "galaxy_user" "example" {
+  username = "example"
+  email = "example@example.com"
+}
+
+resource "galaxy_custom_build" "example" {
+  user_id = galaxy_user.example.id
+  key = "myorg1"
+  name = "My organism v1"
+  len = file("myorg1.len")
+}
+
+resource "galaxy_history" "example" {
+  name = "example"
+  genome_build = galaxy_custom_build.example.key
+}
+
+# Build imported with terraform import galaxy_custom_build.imported <user_id>/<key>, Galaxy does not report its source
+resource "galaxy_custom_build" "imported" {
+  user_id = galaxy_user.example.id
+  key = "myorg2"
+  name = "My organism v2"
+  len = file("myorg2.len")
+
+  lifecycle {
+    ignore_changes = [fasta_id, len_id, len]
+  }
+}
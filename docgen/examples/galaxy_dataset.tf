@@ -0,0 +1,16 @@
+resource "galaxy_history" "example" {
+  name = "example"
+}
+
+resource "galaxy_dataset" "example" {
+  history_id = galaxy_history.example.id
+  path = "reference.fasta"
+  file_type = "fasta"
+  dbkey = "hg38"
+  tags = ["name:reference"]
+}
+
+resource "galaxy_dataset" "remote" {
+  history_id = galaxy_history.example.id
+  url = "https://example.com/annotations.gff3"
+}
\ No newline at end of file
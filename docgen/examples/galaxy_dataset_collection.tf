@@ -0,0 +1,32 @@
+resource "galaxy_history" "example" {
+  name = "example"
+}
+
+resource "galaxy_dataset" "forward" {
+  history_id = galaxy_history.example.id
+  path = "sample1_R1.fastq"
+  file_type = "fastqsanger"
+}
+
+resource "galaxy_dataset" "reverse" {
+  history_id = galaxy_history.example.id
+  path = "sample1_R2.fastq"
+  file_type = "fastqsanger"
+}
+
+resource "galaxy_dataset_collection" "example" {
+  history_id = galaxy_history.example.id
+  name = "samples"
+  collection_type = "list:paired"
+  element {
+    identifier = "sample1"
+    element {
+      identifier = "forward"
+      id = galaxy_dataset.forward.id
+    }
+    element {
+      identifier = "reverse"
+      id = galaxy_dataset.reverse.id
+    }
+  }
+}
\ No newline at end of file
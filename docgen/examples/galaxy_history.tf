@@ -1,3 +1,10 @@
 resource "galaxy_history" "example" {
   name = "example"
+}
+
+resource "galaxy_history" "training" {
+  name = "Training data"
+  published = true
+  importable = true
+  shared_with_users = ["instructor@example.com"]
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+resource "galaxy_history" "reference" {
+  name = "reference"
+}
+
+resource "galaxy_history_export" "example" {
+  history_id = galaxy_history.reference.id
+  format = "tar.gz"
+  path = "reference_history.tar.gz"
+  triggers = {
+    size = galaxy_history.reference.size
+  }
+}
+
+# Restore the snapshot on another instance
+resource "galaxy_history" "restored" {
+  provider = galaxy.production
+  name = "reference"
+  source_archive = galaxy_history_export.example.path
+}
\ No newline at end of file
@@ -1,3 +1,36 @@
 resource "galaxy_stored_workflow" "example" {
   json = file("workflow.ga")
-}
\ No newline at end of file
+}
+
+data "galaxy_workflow_repositories" "checked" {
+  json = file("checked.ga")
+}
+
+resource "galaxy_repository" "checked" {
+  for_each           = data.galaxy_workflow_repositories.checked.repositories
+  tool_shed          = each.value.tool_shed
+  owner              = each.value.owner
+  name               = each.value.name
+  changeset_revision = each.value.changeset_revision
+}
+
+resource "galaxy_stored_workflow" "checked" {
+  json          = file("checked.ga")
+  require_tools = true
+  dynamic "pending_repositories" {
+    for_each = galaxy_repository.checked
+    content {
+      tool_shed = pending_repositories.value.tool_shed
+      owner     = pending_repositories.value.owner
+      name      = pending_repositories.value.name
+    }
+  }
+}
+
+resource "galaxy_stored_workflow" "iwc" {
+  source {
+    trs_server  = "https://dockstore.org/api/ga4gh/trs/v2"
+    trs_id      = "#workflow/github.com/iwc-workflows/parallel-accession-download/main"
+    trs_version = "v0.1.14"
+  }
+}
@@ -6,4 +6,45 @@ resource "galaxy_user" "example" {
   username = "example"
   password = var.password
   email = "example@example.com"
-}
\ No newline at end of file
+}
+
+variable "lab_group_id" {
+  type = string
+}
+
+# Add a new lab member to the lab group so that the group quota applies
+resource "galaxy_user" "lab_member" {
+  username = "member"
+  email = "member@example.com"
+  manage_membership = true
+  groups = [var.lab_group_id]
+}
+
+# User authenticated by OIDC, deactivated rather than deleted when removed
+resource "galaxy_user" "oidc" {
+  username = "oidc-user"
+  email = "oidc-user@example.com"
+  on_destroy = "deactivate"
+}
+
+# Service account routed to a dedicated cluster partition
+resource "galaxy_user" "service" {
+  username = "pipeline"
+  email = "pipeline@example.com"
+  manage_extra_preferences = true
+  extra_preferences = {
+    "cluster|partition" = "pipelines"
+  }
+}
+
+# Report accurate usage for quota audits
+resource "galaxy_user" "audited" {
+  username = "audited"
+  email = "audited@example.com"
+  recalculate_disk_usage = true
+  purge_deleted_datasets = true
+}
+
+output "audited_disk_usage" {
+  value = galaxy_user.audited.total_disk_usage
+}
@@ -0,0 +1,11 @@
+resource "galaxy_user" "automation" {
+  username = "automation"
+  email    = "automation@example.com"
+}
+
+resource "galaxy_user_api_key" "automation" {
+  user_id = galaxy_user.automation.id
+  rotation_triggers = {
+    quarter = "2024-Q1"
+  }
+}
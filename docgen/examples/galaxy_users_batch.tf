@@ -0,0 +1,29 @@
+variable "attendees" {
+  type = list(object({
+    email = string
+    username = string
+  }))
+}
+
+resource "galaxy_users_batch" "workshop" {
+  on_destroy = "purge"
+
+  dynamic "user" {
+    for_each = var.attendees
+    content {
+      email = user.value.email
+      username = user.value.username
+      expiry = "2026-12-31T23:59:59Z"
+    }
+  }
+}
+
+output "workshop_credentials" {
+  value = {
+    for email, id in galaxy_users_batch.workshop.ids : email => {
+      password = galaxy_users_batch.workshop.passwords[email]
+      api_key = galaxy_users_batch.workshop.api_keys[email]
+    }
+  }
+  sensitive = true
+}
@@ -0,0 +1,36 @@
+resource "galaxy_stored_workflow" "build" {
+  json = file("reference_build.ga")
+}
+
+resource "galaxy_history" "nightly" {
+  name = "nightly reference build"
+}
+
+resource "galaxy_dataset" "genome" {
+  history_id = galaxy_history.nightly.id
+  url = "https://example.com/genome.fasta"
+  file_type = "fasta"
+}
+
+resource "galaxy_workflow_invocation" "example" {
+  workflow_id = galaxy_stored_workflow.build.id
+  history_id = galaxy_history.nightly.id
+  input {
+    label = "genome"
+    id = galaxy_dataset.genome.id
+  }
+  input {
+    label = "min_length"
+    value = "500"
+  }
+  step_parameter {
+    step = "filter"
+    name = "cutoff"
+    value = "0.5"
+  }
+  wait_for_completion = true
+}
+
+output "index" {
+  value = galaxy_workflow_invocation.example.outputs["index"]
+}
\ No newline at end of file
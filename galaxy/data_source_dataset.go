@@ -0,0 +1,138 @@
+package galaxy
+
+import (
+	"context"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceDataset() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceDatasetRead,
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"id", "history_id"},
+				Description:  "Dataset id",
+			},
+			"history_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"id", "history_id"},
+				Description:  "Id of history to search for dataset",
+			},
+			"name": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"id", "hid"},
+				Description:   "Name of dataset to search for within history. If more than one dataset matches, the most recent is returned.",
+			},
+			"hid": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"id", "name"},
+				Description:   "Index of dataset within history",
+			},
+			"tags": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "List of tags assigned to dataset",
+			},
+			"dbkey": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Genome build assigned to dataset",
+			},
+			"state": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "State of dataset",
+			},
+			"file_size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Size of dataset in bytes",
+			},
+			"extension": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Datatype extension of dataset",
+			},
+			"misc_info": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Miscellaneous information",
+			},
+			"create_time": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Time dataset created",
+			},
+			"update_time": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Time dataset last modified",
+			},
+			"deleted": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Deleted",
+			},
+			"purged": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Purged",
+			},
+		},
+		Description: "Loads information related to an existing dataset (HDA), by id or by name or hid within a history",
+	}
+}
+
+func dataSourceDatasetRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+	var id string
+	if datasetID, ok := d.GetOk("id"); ok {
+		id = datasetID.(string)
+	}
+	if historyID, ok := d.GetOk("history_id"); ok {
+		name, hasName := d.GetOk("name")
+		hid, hasHid := d.GetOk("hid")
+		if !hasName && !hasHid {
+			return diag.Errorf("name or hid must be provided with history_id")
+		}
+		if items, err := listHistoryContents(ctx, g, historyID.(string)); err == nil {
+			var found *historyContent
+			for _, item := range items {
+				if item.HistoryContentType != "dataset" || item.Deleted {
+					continue
+				}
+				if (hasName && item.Name == name.(string)) || (hasHid && item.Hid == uint(hid.(int))) {
+					if found == nil || item.Hid > found.Hid {
+						found = item
+					}
+				}
+			}
+			if found == nil {
+				return diag.Errorf("dataset not found in history %v", historyID)
+			}
+			id = found.Id
+		} else {
+			return diag.FromErr(err)
+		}
+	}
+
+	if hda, err := getHDA(ctx, g, id); err == nil {
+		return hdaToSchema(hda, d)
+	} else {
+		return diag.FromErr(err)
+	}
+}
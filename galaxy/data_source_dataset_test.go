@@ -0,0 +1,31 @@
+package galaxy_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"testing"
+)
+
+const DatasetPath = "./test-fixtures/data_dataset.tf"
+
+func TestAccDatasetDataSource_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(DatasetPath, t)
+	name := "test"
+	resourceName := "data.galaxy_dataset." + name
+	type tmplFields struct {
+		Name        string
+		DatasetName string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, DatasetName: "reference.txt"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair(resourceName, "id", "galaxy_dataset.test", "id"),
+					resource.TestCheckResourceAttr(resourceName, "extension", "txt"),
+				),
+			},
+		},
+	})
+}
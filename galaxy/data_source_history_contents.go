@@ -0,0 +1,188 @@
+package galaxy
+
+import (
+	"context"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/histories"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"path"
+	"strings"
+)
+
+// Summary of a HDA or HDCA as listed by the history contents API
+type historyContent struct {
+	Id                 blend4go.GalaxyID `json:"id,omitempty"`
+	Name               string            `json:"name,omitempty"`
+	Hid                uint              `json:"hid,omitempty"`
+	HistoryContentType string            `json:"history_content_type,omitempty"`
+	State              string            `json:"state,omitempty"`
+	Extension          string            `json:"extension,omitempty"`
+	FileSize           uint              `json:"file_size,omitempty"`
+	CollectionType     string            `json:"collection_type,omitempty"`
+	Deleted            bool              `json:"deleted,omitempty"`
+	Visible            bool              `json:"visible,omitempty"`
+	Purged             bool              `json:"purged,omitempty"`
+}
+
+var historyContentKeys = []string{"id", "name", "hid", "history_content_type", "state", "extension", "file_size", "collection_type", "deleted", "visible", "purged"}
+
+// List the contents of a history
+func listHistoryContents(ctx context.Context, g *blend4go.GalaxyInstance, historyID blend4go.GalaxyID) ([]*historyContent, error) {
+	// GET /api/histories/{history_id}/contents
+	if res, err := g.R(ctx).SetResult(&[]*historyContent{}).SetQueryParams(map[string]string{
+		"v":    "dev",
+		"keys": strings.Join(historyContentKeys, ","),
+	}).Get(path.Join(histories.BasePath, historyID, "contents")); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			return *result.(*[]*historyContent), nil
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+func dataSourceHistoryContents() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceHistoryContentsRead,
+		Schema: map[string]*schema.Schema{
+			"history_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Id of history to list",
+			},
+			"type": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateDiagFunc: func(v interface{}, path cty.Path) diag.Diagnostics {
+					switch v.(string) {
+					default:
+						diags := diag.Errorf("invalid content type %s", v)
+						diags[0].AttributePath = path
+						return diags
+					case "dataset":
+					case "dataset_collection":
+					}
+					return nil
+				},
+				Description: "Only list items of type (dataset, dataset_collection)",
+			},
+			"state": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list items in state",
+			},
+			"extension": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list datasets with datatype extension",
+			},
+			"deleted": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "List deleted items rather than undeleted items",
+			},
+			"visible": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "List visible items rather than hidden items",
+			},
+			"contents": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "HDA or HDCA id",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Name as displayed to user",
+						},
+						"hid": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Index of item within history",
+						},
+						"type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Item type (dataset, dataset_collection)",
+						},
+						"state": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Item state",
+						},
+						"extension": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Datatype extension of dataset",
+						},
+						"file_size": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Size of dataset in bytes",
+						},
+						"collection_type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Collection type of dataset collection",
+						},
+					},
+				},
+				Description: "List of history items matching the filters, ordered by hid",
+			},
+		},
+		Description: "Lists the datasets and dataset collections within a history",
+	}
+}
+
+func dataSourceHistoryContentsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+	historyID := d.Get("history_id").(string)
+
+	if items, err := listHistoryContents(ctx, g, historyID); err == nil {
+		contentType := d.Get("type").(string)
+		state := d.Get("state").(string)
+		extension := d.Get("extension").(string)
+		deleted := d.Get("deleted").(bool)
+		visible := d.Get("visible").(bool)
+
+		contents := []map[string]interface{}{}
+		for _, item := range items {
+			if (contentType != "" && item.HistoryContentType != contentType) ||
+				(state != "" && item.State != state) ||
+				(extension != "" && item.Extension != extension) ||
+				item.Deleted != deleted || item.Visible != visible {
+				continue
+			}
+			contents = append(contents, map[string]interface{}{
+				"id":              item.Id,
+				"name":            item.Name,
+				"hid":             item.Hid,
+				"type":            item.HistoryContentType,
+				"state":           item.State,
+				"extension":       item.Extension,
+				"file_size":       item.FileSize,
+				"collection_type": item.CollectionType,
+			})
+		}
+		if err := d.Set("contents", contents); err != nil {
+			return diag.FromErr(err)
+		}
+
+		d.SetId(historyID)
+	} else {
+		return diag.FromErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package galaxy_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"testing"
+)
+
+const HistoryContentsPath = "./test-fixtures/history_contents.tf"
+
+func TestAccHistoryContents_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(HistoryContentsPath, t)
+	name := "test"
+	resourceName := "data.galaxy_history_contents." + name
+	type tmplFields struct {
+		Name string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "contents.#", "1"),
+					resource.TestCheckResourceAttrPair(resourceName, "contents.0.id", "galaxy_dataset.test", "id"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,247 @@
+package galaxy
+
+import (
+	"context"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/workflows"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"path"
+)
+
+var storedWorkflowDataOmitFields = map[string]interface{}{"inputs": nil, "steps": nil, "model_class": nil}
+
+// Workflow export styles keyed on format
+var workflowExportStyles = map[string]string{
+	"ga":      "export",
+	"format2": "format2",
+}
+
+func dataSourceStoredWorkflow() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceStoredWorkflowRead,
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"id", "name", "slug"},
+				Description:  "Stored workflow id",
+			},
+			"name": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"id", "name", "slug"},
+				Description:  "Name of workflow owned by the provider user to search for. If more than one workflow matches, the most recently updated is returned.",
+			},
+			"slug": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"id", "name", "slug"},
+				RequiredWith: []string{"owner"},
+				Description:  "Slug of published workflow to search for",
+			},
+			"owner": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				RequiredWith: []string{"slug"},
+				Description:  "Username of owner of published workflow",
+			},
+			"format": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "ga",
+				ValidateDiagFunc: func(v interface{}, path cty.Path) diag.Diagnostics {
+					if _, ok := workflowExportStyles[v.(string)]; !ok {
+						diags := diag.Errorf("invalid workflow format %s", v)
+						diags[0].AttributePath = path
+						return diags
+					}
+					return nil
+				},
+				Description: "Format of exported workflow (ga, format2)",
+			},
+			"json": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Exported workflow, suitable for the json argument of galaxy_stored_workflow",
+			},
+			"tags": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "List of tags assigned to workflow",
+			},
+			"deleted": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Workflow deleted",
+			},
+			"latest_workflow_uuid": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "UUID to uniquely identify stored workflow",
+			},
+			"show_in_tool_panel": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Show in tool panel in Galaxy UI",
+			},
+			"url": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "URL of workflow within Galaxy API",
+			},
+			"number_of_steps": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Count of steps in workflow",
+			},
+			"published": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Workflow available to all users",
+			},
+			"importable": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Users can import workflow",
+			},
+			"username_and_slug": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Username and slug, the path of the published URL of the workflow relative to the Galaxy host",
+			},
+			"annotation": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Workflow annotation",
+			},
+			"version": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Workflow version",
+			},
+		},
+		Description: "Loads an existing stored workflow by id, name, or published owner and slug, and exports its content. Combined with a galaxy_stored_workflow resource of another provider alias, workflows can be promoted between Galaxy instances.",
+	}
+}
+
+// Get sharing status of stored workflow
+func getWorkflowSharing(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID) (*sharingStatus, error) {
+	// GET /api/workflows/{id}/sharing
+	if res, err := g.R(ctx).SetResult(&sharingStatus{}).Get(path.Join(workflows.BasePath, id, "sharing")); err == nil {
+		if endpointUnsupported(res.StatusCode(), res.Header().Get("Content-Type")) {
+			return nil, errEndpointUnsupported
+		}
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			return result.(*sharingStatus), nil
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Export stored workflow in the given format
+func exportWorkflow(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID, format string) (string, error) {
+	// GET /api/workflows/{id}/download
+	if res, err := g.R(ctx).SetQueryParams(map[string]string{
+		"style":  workflowExportStyles[format],
+		"format": "json-download",
+	}).Get(path.Join(workflows.BasePath, id, "download")); err == nil {
+		if _, err := blend4go.HandleResponse(res); err == nil {
+			return string(res.Body()), nil
+		} else {
+			return "", err
+		}
+	} else {
+		return "", err
+	}
+}
+
+func dataSourceStoredWorkflowRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+	var id string
+	if workflowID, ok := d.GetOk("id"); ok {
+		id = workflowID.(string)
+	}
+	if name, ok := d.GetOk("name"); ok {
+		// Workflows are listed most recently updated first
+		if list, err := workflows.List(ctx, g, false, false, false, false); err == nil {
+			for _, workflow := range list {
+				if workflow.Name == name.(string) {
+					id = workflow.Id
+					break
+				}
+			}
+		} else {
+			return diag.FromErr(err)
+		}
+		if id == "" {
+			return diag.Errorf("workflow %v not found", name)
+		}
+	}
+	if slug, ok := d.GetOk("slug"); ok {
+		owner := d.Get("owner").(string)
+		if list, err := workflows.List(ctx, g, true, false, false, false); err == nil {
+			for _, workflow := range list {
+				if workflow.Owner != owner || !workflow.Published {
+					continue
+				}
+				if status, err := getWorkflowSharing(ctx, g, workflow.Id); err == nil {
+					if status.UsernameAndSlug != "" && path.Base(status.UsernameAndSlug) == slug.(string) {
+						id = workflow.Id
+						break
+					}
+				} else {
+					return diag.FromErr(err)
+				}
+			}
+		} else {
+			return diag.FromErr(err)
+		}
+		if id == "" {
+			return diag.Errorf("published workflow %v/%v not found", owner, slug)
+		}
+	}
+
+	workflow, err := workflows.Get(ctx, g, id)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	diags := toSchema(workflow, d, storedWorkflowDataOmitFields)
+
+	status, err := getWorkflowSharing(ctx, g, id)
+	if err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	var slug string
+	if status.UsernameAndSlug != "" {
+		slug = path.Base(status.UsernameAndSlug)
+	}
+
+	content, err := exportWorkflow(ctx, g, id, d.Get("format").(string))
+	if err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+
+	for k, v := range map[string]interface{}{
+		"importable":        status.Importable,
+		"slug":              slug,
+		"username_and_slug": status.UsernameAndSlug,
+		"json":              content,
+	} {
+		if err := d.Set(k, v); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	return diags
+}
@@ -0,0 +1,44 @@
+package galaxy_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"regexp"
+	"testing"
+)
+
+const StoredWorkflowDataPath = "./test-fixtures/data_stored_workflow.tf"
+
+func TestAccStoredWorkflowDataSource_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(StoredWorkflowDataPath, t)
+	name := "test"
+	resourceName := "data.galaxy_stored_workflow." + name
+	workflow, parsedWorkflow, err := loadWorkflow(WorkflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type tmplFields struct {
+		Name   string
+		Json   string
+		Format string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: workflow, Format: "ga"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair(resourceName, "id", "galaxy_stored_workflow.test", "id"),
+					resource.TestCheckResourceAttr(resourceName, "name", parsedWorkflow["name"].(string)),
+					resource.TestMatchResourceAttr(resourceName, "json", regexp.MustCompile(`"a_galaxy_workflow"`)),
+				),
+			},
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: workflow, Format: "format2"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr(resourceName, "json", regexp.MustCompile(`GalaxyWorkflow`)),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,180 @@
+package galaxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/users"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceUsers() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceUsersRead,
+		Schema: map[string]*schema.Schema{
+			"email": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list the user with email address",
+			},
+			"username": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list the user with username",
+			},
+			"search": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only list users with an email address or username containing the pattern",
+			},
+			"deleted": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "List deleted users rather than undeleted users",
+			},
+			"users": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "User id",
+						},
+						"email": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Users email address",
+						},
+						"username": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Username to identify user",
+						},
+						"quota_percent": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Percent of storage quota used",
+						},
+						"total_disk_usage": {
+							Type:        schema.TypeFloat,
+							Computed:    true,
+							Description: "Total disk usage of users stored data",
+						},
+						"quota": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Maximum disk storage available to user",
+						},
+						"is_admin": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "User is administrator",
+						},
+					},
+				},
+				Description: "List of users matching the filters",
+			},
+			"ids_by_email": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Map of user ids keyed on email address",
+			},
+		},
+		Description: "Lists existing users. Requires the provider user to be an administrator, or Galaxy to expose user emails and names. Suitable for resolving the user ids of galaxy_quota.users without managing each user as a resource.",
+	}
+}
+
+// Item of GET /api/users listing the keys of each user reported by the data source
+type userListItem struct {
+	users.User
+	// Versions of Galaxy that ignore keys omit the disk usage of listed users
+	TotalDiskUsage *float32 `json:"total_disk_usage"`
+}
+
+// List users exactly matching the email or username filters with their quota usage.
+// Only users whose usage is not listed are loaded individually.
+func listUserDetails(ctx context.Context, g *blend4go.GalaxyInstance, deleted bool, email, username, search string) ([]*users.User, error) {
+	params := map[string]string{"keys": "id,email,username,quota_percent,total_disk_usage,quota,is_admin"}
+	if deleted {
+		params["deleted"] = "true"
+	}
+	for k, v := range map[string]string{"f_email": email, "f_name": username, "f_any": search} {
+		if v != "" {
+			params[k] = v
+		}
+	}
+	// GET /api/users
+	res, err := g.R(ctx).SetResult(&[]*userListItem{}).SetQueryParams(params).Get(users.BasePath)
+	if err != nil {
+		return nil, err
+	}
+	result, err := blend4go.HandleResponse(res)
+	if err != nil {
+		return nil, err
+	}
+	var list []*users.User
+	for _, item := range *result.(*[]*userListItem) {
+		// Filters match substrings, only report exact matches
+		if (email != "" && item.Email != email) || (username != "" && item.Username != username) {
+			continue
+		}
+		if item.TotalDiskUsage == nil {
+			user, err := users.Get(ctx, g, item.Id, deleted)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, user)
+			continue
+		}
+		item.User.TotalDiskUsage = *item.TotalDiskUsage
+		list = append(list, &item.User)
+	}
+	return list, nil
+}
+
+func dataSourceUsersRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+	email := d.Get("email").(string)
+	username := d.Get("username").(string)
+	search := d.Get("search").(string)
+	deleted := d.Get("deleted").(bool)
+
+	list, err := listUserDetails(ctx, g, deleted, email, username, search)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	result := []map[string]interface{}{}
+	ids := map[string]string{}
+	for _, user := range list {
+		result = append(result, map[string]interface{}{
+			"id":               user.Id,
+			"email":            user.Email,
+			"username":         user.Username,
+			"quota_percent":    user.QuotaPercent,
+			"total_disk_usage": user.TotalDiskUsage,
+			"quota":            user.Quota,
+			"is_admin":         user.IsAdmin,
+		})
+		ids[user.Email] = user.Id
+	}
+
+	var diags diag.Diagnostics
+	for k, v := range map[string]interface{}{
+		"users":        result,
+		"ids_by_email": ids,
+	} {
+		if err := d.Set(k, v); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	d.SetId(HashString(fmt.Sprintf("%v|%v|%v|%v", email, username, search, deleted)))
+	return diags
+}
@@ -0,0 +1,31 @@
+package galaxy_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"testing"
+)
+
+const UsersDataPath = "./test-fixtures/data_users.tf"
+
+func TestAccUsers_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(UsersDataPath, t)
+	name := "test"
+	resourceName := "data.galaxy_users." + name
+	type tmplFields struct {
+		Name string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "users.#", "1"),
+					resource.TestCheckResourceAttrPair(resourceName, "users.0.id", "galaxy_user.test", "id"),
+					resource.TestCheckResourceAttrPair(resourceName, "ids_by_email.test-users@example.com", "galaxy_user.test", "id"),
+				),
+			},
+		},
+	})
+}
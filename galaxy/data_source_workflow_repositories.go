@@ -2,70 +2,143 @@ package galaxy
 
 import (
 	"context"
-	"github.com/brinkmanlab/blend4go/workflows"
+	"github.com/brinkmanlab/blend4go"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"sort"
 )
 
 func dataSourceWorkflowRepositories() *schema.Resource {
+	repositoryElem := map[string]*schema.Schema{
+		"name": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Repository name",
+		},
+		"tool_shed": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Toolshed hostname",
+		},
+		"owner": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Repository owner",
+		},
+		"changeset_revision": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Changeset revision",
+		},
+		"installed": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Repository is installed. Only populated if check_installed is set.",
+		},
+		"tools": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"tool_id": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "Tool id",
+					},
+					"tool_version": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "Tool version",
+					},
+				},
+			},
+			Description: "List of tools of the repository referenced by the workflow",
+		},
+	}
+
 	return &schema.Resource{
 		ReadContext: dataSourceWorkflowRepositoriesRead,
 		Schema: map[string]*schema.Schema{
 			"json": {
 				Type:        schema.TypeString,
 				Required:    true,
-				StateFunc:   func(v interface{}) string { return HashString(v.(string)) },
-				Description: "JSON encoded workflow. See terraform file() to load a .ga file.",
+				StateFunc:   func(v interface{}) string { return HashWorkflow(v.(string)) },
+				Description: "JSON encoded workflow (.ga) or gxformat2 YAML workflow. See terraform file() to load a workflow file.",
+			},
+			"check_installed": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Check which repositories are installed in the Galaxy instance, populating installed and missing_repositories",
 			},
 			"repositories": {
 				Type:     schema.TypeSet,
 				Computed: true,
 				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"name": {
-							Type:        schema.TypeString,
-							Computed:    true,
-							Description: "Repository name",
-						},
-						"tool_shed": {
-							Type:        schema.TypeString,
-							Computed:    true,
-							Description: "Toolshed hostname",
-						},
-						"owner": {
-							Type:        schema.TypeString,
-							Computed:    true,
-							Description: "Repository owner",
-						},
-						"changeset_revision": {
-							Type:        schema.TypeString,
-							Computed:    true,
-							Description: "Changeset revision",
-						},
-					},
+					Schema: repositoryElem,
 				},
-				Description: "Set of repositories referenced within workflow",
+				Description: "Set of repositories referenced within workflow and its subworkflows",
+			},
+			"missing_repositories": {
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: repositoryElem,
+				},
+				Description: "Set of repositories referenced within workflow that are not installed. Only populated if check_installed is set.",
 			},
 		},
 		Description: "Galaxy workflows are dependant on the presence of the tools they use to be installed in the same Galaxy instance. The data source extracts the tool repositories referenced within the workflow json, listing them for installation. See [resource_repository](../resources/resource_repository) for more information on installing repositories.",
 	}
 }
 
-func dataSourceWorkflowRepositoriesRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+func dataSourceWorkflowRepositoriesRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
 	json := d.Get("json").(string)
-	hash := HashString(json)
-	if repos, err := workflows.Repositories(json); err == nil {
-		r := make([]map[string]string, len(repos))
+	hash := HashWorkflow(json)
+	if repos, err := workflowRepositories(json); err == nil {
+		var installed map[string]bool
+		checkInstalled := d.Get("check_installed").(bool)
+		if checkInstalled {
+			if installed, err = installedRepositories(ctx, g); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+
+		r := make([]map[string]interface{}, len(repos))
+		missing := make([]map[string]interface{}, 0)
 		for i, repo := range repos {
-			r[i] = map[string]string{
+			toolIDs := make([]string, 0, len(repo.Tools))
+			for toolID := range repo.Tools {
+				toolIDs = append(toolIDs, toolID)
+			}
+			sort.Strings(toolIDs)
+			tools := make([]map[string]string, len(toolIDs))
+			for j, toolID := range toolIDs {
+				tools[j] = map[string]string{
+					"tool_id":      toolID,
+					"tool_version": repo.Tools[toolID],
+				}
+			}
+			r[i] = map[string]interface{}{
 				"name":               repo.Name,
 				"tool_shed":          repo.ToolShed,
 				"owner":              repo.Owner,
 				"changeset_revision": repo.ChangesetRevision,
+				"installed":          installed[repo.GetID()],
+				"tools":              tools,
+			}
+			if checkInstalled && !installed[repo.GetID()] {
+				missing = append(missing, r[i])
 			}
 		}
-		if err := d.Set("repositories", r); err != nil {
-			return diag.FromErr(err)
+		for k, v := range map[string]interface{}{
+			"repositories":         r,
+			"missing_repositories": missing,
+		} {
+			if err := d.Set(k, v); err != nil {
+				return diag.FromErr(err)
+			}
 		}
 
 		d.SetId(hash)
@@ -1,7 +1,9 @@
 package galaxy_test
 
 import (
+	"encoding/json"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"io/ioutil"
 	"testing"
 )
 
@@ -28,6 +30,78 @@ func TestAccWorkflowRepositories_basic(t *testing.T) {
 				Check: resource.ComposeAggregateTestCheckFunc(
 					testCheckResourceAttrEqual(resourceName, "repositories.#", len(parsedWorkflow["steps"].(map[string]interface{}))),
 					resource.TestCheckResourceAttr(resourceName, "repositories.0.name", "awkscript"),
+					resource.TestCheckResourceAttr(resourceName, "repositories.0.tools.0.tool_version", "1.0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccWorkflowRepositories_format2(t *testing.T) {
+	tmpl := testAccConfigTemplate(WorkflowRepositoriesPath, t)
+	name := "test"
+	resourceName := "data.galaxy_workflow_repositories." + name
+	workflow, err := ioutil.ReadFile(WorkflowFormat2Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type tmplFields struct {
+		Name string
+		Json string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: string(workflow)}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "repositories.#", "1"),
+					resource.TestCheckResourceAttr(resourceName, "repositories.0.name", "awkscript"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccWorkflowRepositories_subworkflow(t *testing.T) {
+	tmpl := testAccConfigTemplate(WorkflowRepositoriesPath, t)
+	name := "test"
+	resourceName := "data.galaxy_workflow_repositories." + name
+	_, parsedWorkflow, err := loadWorkflow(WorkflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Embed the workflow as a subworkflow
+	workflow, err := json.Marshal(map[string]interface{}{
+		"a_galaxy_workflow": "true",
+		"format-version":    "0.1",
+		"name":              "Parent workflow",
+		"steps": map[string]interface{}{
+			"0": map[string]interface{}{
+				"id":          0,
+				"type":        "subworkflow",
+				"subworkflow": parsedWorkflow,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type tmplFields struct {
+		Name string
+		Json string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: string(workflow)}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "repositories.#", "1"),
+					resource.TestCheckResourceAttr(resourceName, "repositories.0.name", "awkscript"),
+					resource.TestCheckResourceAttr(resourceName, "repositories.0.tools.0.tool_id", "toolshed.g2.bx.psu.edu/repos/brinkmanlab/awkscript/awkscript/1.0"),
 				),
 			},
 		},
@@ -63,16 +63,27 @@ func Provider() *schema.Provider {
 		},
 		ConfigureContextFunc: providerConfigure,
 		ResourcesMap: map[string]*schema.Resource{
-			"galaxy_user":            resourceUser(),
-			"galaxy_stored_workflow": resourceStoredWorkflow(),
-			"galaxy_job":             resourceJob(),
-			"galaxy_repository":      resourceRepository(),
-			"galaxy_history":         resourceHistory(),
-			"galaxy_quota":           resourceQuota(),
+			"galaxy_user":                resourceUser(),
+			"galaxy_stored_workflow":     resourceStoredWorkflow(),
+			"galaxy_job":                 resourceJob(),
+			"galaxy_repository":          resourceRepository(),
+			"galaxy_history":             resourceHistory(),
+			"galaxy_quota":               resourceQuota(),
+			"galaxy_dataset":             resourceDataset(),
+			"galaxy_dataset_collection":  resourceDatasetCollection(),
+			"galaxy_history_export":      resourceHistoryExport(),
+			"galaxy_workflow_invocation": resourceWorkflowInvocation(),
+			"galaxy_user_api_key":        resourceUserAPIKey(),
+			"galaxy_users_batch":         resourceUsersBatch(),
+			"galaxy_custom_build":        resourceCustomBuild(),
 		},
 		DataSourcesMap: map[string]*schema.Resource{
 			"galaxy_workflow_repositories": dataSourceWorkflowRepositories(),
 			"galaxy_tool":                  dataSourceTool(),
+			"galaxy_dataset":               dataSourceDataset(),
+			"galaxy_history_contents":      dataSourceHistoryContents(),
+			"galaxy_stored_workflow":       dataSourceStoredWorkflow(),
+			"galaxy_users":                 dataSourceUsers(),
 		},
 	}
 }
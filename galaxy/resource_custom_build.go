@@ -0,0 +1,182 @@
+package galaxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/users"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Custom build sources keyed on attribute
+var customBuildSources = map[string]string{
+	"fasta_id": "fasta",
+	"len_id":   "file",
+	"len":      "text",
+}
+
+func resourceCustomBuild() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceCustomBuildCreate,
+		ReadContext:   resourceCustomBuildRead,
+		DeleteContext: resourceCustomBuildDelete,
+		Schema: map[string]*schema.Schema{
+			//"id": {
+			//	Type:     schema.TypeString,
+			//	Computed: true,
+			//},
+			"user_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of user to create custom build for",
+			},
+			"key": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Database key (dbkey) of build, assignable to galaxy_history.genome_build and galaxy_dataset.dbkey",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Name of build as displayed to user",
+			},
+			"fasta_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"fasta_id", "len_id", "len"},
+				Description:  "Id of FASTA HDA to derive chromosome lengths from. Galaxy runs a conversion job to calculate the lengths.",
+			},
+			"len_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"fasta_id", "len_id", "len"},
+				Description:  "Id of len HDA listing chromosome names and lengths",
+			},
+			"len": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"fasta_id", "len_id", "len"},
+				Description:  "Chromosome names and lengths, one tab separated pair per line. See terraform file() to load a len file.",
+			},
+			"chromosome_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Number of chromosomes in build, available once Galaxy has processed the source",
+			},
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: func(ctx context.Context, d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+				parts := strings.SplitN(d.Id(), "/", 2)
+				if len(parts) != 2 {
+					return nil, fmt.Errorf("expected import id in the form user_id/key, got %v", d.Id())
+				}
+				for k, v := range map[string]string{"user_id": parts[0], "key": parts[1]} {
+					if err := d.Set(k, v); err != nil {
+						return nil, err
+					}
+				}
+				return []*schema.ResourceData{d}, nil
+			},
+		},
+		Description: "Custom genome builds (dbkeys) allow users to assign builds of non-model organisms to their datasets. Builds belong to a user and are defined by a FASTA dataset or a list of chromosome lengths. Galaxy does not report the source of a build, imported builds are replaced by the next apply unless fasta_id, len_id and len are listed in lifecycle ignore_changes.",
+	}
+}
+
+// Item of GET /api/users/{id}/custom_builds
+type customBuild struct {
+	Id    string      `json:"id"`
+	Name  string      `json:"name"`
+	Count interface{} `json:"count"`
+}
+
+// Create custom build of user. Galaxy refuses to replace an existing build, changes to a build replace the resource.
+func putCustomBuild(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) error {
+	body := map[string]string{"name": d.Get("name").(string)}
+	for attr, lenType := range customBuildSources {
+		if v, ok := d.GetOk(attr); ok {
+			body["len|type"] = lenType
+			body["len|value"] = v.(string)
+		}
+	}
+	// PUT /api/users/{id}/custom_builds/{key}
+	if res, err := g.R(ctx).SetBody(body).Put(path.Join(users.BasePath, d.Get("user_id").(string), "custom_builds", d.Get("key").(string))); err == nil {
+		_, err := blend4go.HandleResponse(res)
+		return err
+	} else {
+		return err
+	}
+}
+
+func resourceCustomBuildCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	if err := putCustomBuild(ctx, g, d); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(path.Join(d.Get("user_id").(string), d.Get("key").(string)))
+	return resourceCustomBuildRead(ctx, d, m)
+}
+
+func resourceCustomBuildRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	// GET /api/users/{id}/custom_builds
+	if res, err := g.R(ctx).SetResult(&[]*customBuild{}).Get(path.Join(users.BasePath, d.Get("user_id").(string), "custom_builds")); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			for _, build := range *result.(*[]*customBuild) {
+				if build.Id != d.Get("key").(string) {
+					continue
+				}
+				// Galaxy reports the count as a number, or as a string read from the len file
+				var count int
+				switch c := build.Count.(type) {
+				case float64:
+					count = int(c)
+				case string:
+					count, _ = strconv.Atoi(c)
+				}
+				var diags diag.Diagnostics
+				for k, v := range map[string]interface{}{
+					"name":             build.Name,
+					"chromosome_count": count,
+				} {
+					if err := d.Set(k, v); err != nil {
+						diags = append(diags, diag.FromErr(err)...)
+					}
+				}
+				return diags
+			}
+			// Build was deleted outside of terraform
+			d.SetId("")
+		} else {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceCustomBuildDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	// DELETE /api/users/{id}/custom_builds/{key}
+	if res, err := g.R(ctx).Delete(path.Join(users.BasePath, d.Get("user_id").(string), "custom_builds", d.Get("key").(string))); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	return nil
+}
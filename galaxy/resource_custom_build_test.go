@@ -0,0 +1,44 @@
+package galaxy_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"testing"
+)
+
+const CustomBuildResourcePath = "test-fixtures/custom_build.tf"
+
+func TestAccCustomBuild_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(CustomBuildResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_custom_build." + name
+	type tmplFields struct {
+		Name      string
+		BuildName string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, BuildName: "Test build"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "key", "test_build"),
+					resource.TestCheckResourceAttr(resourceName, "name", "Test build"),
+				),
+			},
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, BuildName: "Renamed build"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "name", "Renamed build"),
+				),
+			},
+			{
+				// Galaxy does not report the source of a build, see the lifecycle ignore_changes note of galaxy_custom_build
+				ResourceName:            resourceName,
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"len"},
+			},
+		},
+	})
+}
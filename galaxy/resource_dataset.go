@@ -0,0 +1,378 @@
+package galaxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/histories"
+	"github.com/brinkmanlab/blend4go/jobs"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+var datasetEnded = map[string]bool{
+	"new":              false,
+	"upload":           false,
+	"queued":           false,
+	"running":          false,
+	"setting_metadata": false,
+	"ok":               true,
+	"empty":            true,
+	"error":            true,
+	"failed_metadata":  true,
+	"discarded":        true,
+	"paused":           true,
+	"deferred":         true,
+}
+
+func resourceDataset() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceDatasetCreate,
+		ReadContext:   resourceDatasetRead,
+		UpdateContext: resourceDatasetUpdate,
+		DeleteContext: resourceDatasetDelete,
+		CustomizeDiff: resourceDatasetCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			//"id": {
+			//	Type:     schema.TypeString,
+			//	Computed: true,
+			//},
+			"history_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of history to upload dataset to",
+			},
+			"path": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"path", "url", "content"},
+				Description:  "Path to local file to upload. The file is read and uploaded by the provider.",
+			},
+			"url": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"path", "url", "content"},
+				Description:  "URL for Galaxy to fetch dataset contents from",
+			},
+			"content": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"path", "url", "content"},
+				StateFunc:    func(v interface{}) string { return HashString(v.(string)) },
+				Description:  "Inline dataset contents",
+			},
+			"content_hash": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Hash of the local file uploaded, used to detect changes to the file contents",
+			},
+			"file_type": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "auto",
+				ForceNew:    true,
+				Description: "Galaxy datatype extension of dataset. Galaxy will attempt to detect the datatype if set to 'auto'.",
+			},
+			"dbkey": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "?",
+				Description: "Genome build assigned to dataset",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Dataset name as displayed to user",
+			},
+			"tags": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "List of tags assigned to dataset",
+			},
+			"hid": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Index of dataset within history",
+			},
+			"state": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Upload state of dataset",
+			},
+			"file_size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Size of dataset in bytes",
+			},
+			"extension": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Datatype extension as assigned by Galaxy",
+			},
+			"misc_info": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Miscellaneous information reported by the upload",
+			},
+			"create_time": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Time dataset created",
+			},
+			"update_time": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Time dataset last modified",
+			},
+			"deleted": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Deleted",
+			},
+			"purged": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Purged",
+			},
+			"purge": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Purge dataset on delete",
+			},
+		},
+		Importer:    &schema.ResourceImporter{StateContext: schema.ImportStatePassthroughContext},
+		Description: "Datasets (HDAs) hold data within a history. This resource uploads data into a history using the Galaxy fetch API.",
+	}
+}
+
+// Response of POST /api/tools/fetch
+type fetchResponse struct {
+	Outputs []*histories.HistoryDatasetAssociation `json:"outputs"`
+	Jobs    []*jobs.Job                            `json:"jobs"`
+}
+
+// Get a HDA by id
+func getHDA(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID) (*histories.HistoryDatasetAssociation, error) {
+	// GET /api/datasets/{id}
+	if res, err := g.R(ctx).SetResult(&histories.HistoryDatasetAssociation{}).SetQueryParam("hda_ldda", "hda").Get(path.Join("/api/datasets", id)); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			return result.(*histories.HistoryDatasetAssociation), nil
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Wait for a HDA to reach a terminal state
+func waitForHDA(ctx context.Context, g *blend4go.GalaxyInstance, hda *histories.HistoryDatasetAssociation) (*histories.HistoryDatasetAssociation, error) {
+	for !datasetEnded[hda.State] {
+		time.Sleep(2 * time.Second)
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return hda, ctx.Err()
+		}
+		if res, err := getHDA(ctx, g, hda.Id); err == nil {
+			hda = res
+		} else {
+			return hda, err
+		}
+	}
+	return hda, nil
+}
+
+func hdaToSchema(hda *histories.HistoryDatasetAssociation, d *schema.ResourceData) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for k, v := range map[string]interface{}{
+		"history_id":  hda.HistoryId,
+		"name":        hda.Name,
+		"tags":        hda.Tags,
+		"dbkey":       hda.GenomeBuild,
+		"hid":         hda.Hid,
+		"state":       hda.State,
+		"file_size":   hda.FileSize,
+		"extension":   hda.Extension,
+		"misc_info":   hda.MiscInfo,
+		"create_time": hda.CreateTime,
+		"update_time": hda.UpdateTime,
+		"deleted":     hda.Deleted,
+		"purged":      hda.Purged,
+	} {
+		if err := d.Set(k, v); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	d.SetId(hda.Id)
+	return diags
+}
+
+func resourceDatasetCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	// Replace the dataset if the contents of the local file change
+	if p, ok := d.GetOk("path"); ok && d.Id() != "" {
+		if data, err := ioutil.ReadFile(p.(string)); err == nil {
+			if hash := HashString(string(data)); hash != d.Get("content_hash").(string) {
+				if err := d.SetNew("content_hash", hash); err != nil {
+					return err
+				}
+				return d.ForceNew("content_hash")
+			}
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+func resourceDatasetCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	element := map[string]interface{}{
+		"ext":   d.Get("file_type").(string),
+		"dbkey": d.Get("dbkey").(string),
+	}
+	if name, ok := d.GetOk("name"); ok {
+		element["name"] = name.(string)
+	}
+	if tags, ok := d.GetOk("tags"); ok {
+		element["tags"] = tags.([]interface{})
+	}
+
+	var data []byte
+	if p, ok := d.GetOk("path"); ok {
+		var err error
+		if data, err = ioutil.ReadFile(p.(string)); err != nil {
+			return diag.FromErr(err)
+		}
+		element["src"] = "files"
+		if _, ok := element["name"]; !ok {
+			element["name"] = filepath.Base(p.(string))
+		}
+	}
+	if u, ok := d.GetOk("url"); ok {
+		element["src"] = "url"
+		element["url"] = u.(string)
+	}
+	if content, ok := d.GetOk("content"); ok {
+		element["src"] = "pasted"
+		element["paste_content"] = content.(string)
+	}
+
+	targets := []map[string]interface{}{{
+		"destination": map[string]string{"type": "hdas"},
+		"elements":    []map[string]interface{}{element},
+	}}
+
+	// POST /api/tools/fetch
+	r := g.R(ctx).SetResult(&fetchResponse{})
+	if data != nil {
+		// Local files must be sent as multipart form data
+		t, err := json.Marshal(targets)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		r.SetFormData(map[string]string{
+			"history_id": d.Get("history_id").(string),
+			"targets":    string(t),
+		}).SetFileReader("files_0|file_data", element["name"].(string), bytes.NewReader(data))
+	} else {
+		r.SetBody(map[string]interface{}{
+			"history_id": d.Get("history_id").(string),
+			"targets":    targets,
+		})
+	}
+
+	if res, err := r.Post("/api/tools/fetch"); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			outputs := result.(*fetchResponse).Outputs
+			if len(outputs) != 1 {
+				return diag.Errorf("unexpected number of outputs when uploading dataset: %v", len(outputs))
+			}
+			var diags diag.Diagnostics
+			hda, err := waitForHDA(ctx, g, outputs[0])
+			if err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+			if data != nil {
+				if err := d.Set("content_hash", HashString(string(data))); err != nil {
+					diags = append(diags, diag.FromErr(err)...)
+				}
+			}
+			diags = append(diags, hdaToSchema(hda, d)...)
+			if hda.State == "error" || hda.State == "failed_metadata" {
+				diags = append(diags, diag.Errorf("galaxy_dataset upload failed, see %v/api/datasets/%v for more info", g.Client.HostURL, hda.Id)...)
+			}
+			return diags
+		} else {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+}
+
+func resourceDatasetRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	if hda, err := getHDA(ctx, g, d.Id()); err == nil {
+		return hdaToSchema(hda, d)
+	} else {
+		return diag.FromErr(err)
+	}
+}
+
+func resourceDatasetUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	tags := []string{}
+	for _, tag := range d.Get("tags").([]interface{}) {
+		tags = append(tags, tag.(string))
+	}
+	body := map[string]interface{}{
+		"name":         d.Get("name").(string),
+		"genome_build": d.Get("dbkey").(string),
+		"tags":         tags,
+	}
+
+	// PUT /api/histories/{history_id}/contents/{id}
+	if res, err := g.R(ctx).SetBody(body).Put(path.Join(histories.BasePath, d.Get("history_id").(string), "contents", d.Id())); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	return resourceDatasetRead(ctx, d, m)
+}
+
+func resourceDatasetDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	// DELETE /api/histories/{history_id}/contents/{id}
+	r := g.R(ctx)
+	if d.Get("purge").(bool) {
+		r.SetQueryParam("purge", "true")
+	}
+	if res, err := r.Delete(path.Join(histories.BasePath, d.Get("history_id").(string), "contents", d.Id())); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	return nil
+}
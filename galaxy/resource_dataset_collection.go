@@ -0,0 +1,308 @@
+package galaxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/histories"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"path"
+	"strings"
+)
+
+func resourceDatasetCollection() *schema.Resource {
+	element := map[string]*schema.Schema{
+		"identifier": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "Element identifier. Paired collections require the identifiers 'forward' and 'reverse'.",
+		},
+		"id": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "Id of dataset",
+		},
+		"src": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Default:     "hda",
+			Description: "Source of dataset (hda, ldda)",
+		},
+	}
+
+	nestedElement := map[string]*schema.Schema{
+		"id": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Id of dataset. Omit and specify nested element blocks to create a sub-collection.",
+		},
+		"element": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Resource{
+				Schema: element,
+			},
+			Description: "Repeatable block of elements of a sub-collection, used for nested collection types such as list:paired",
+		},
+	}
+
+	// Copy in common element fields
+	for k, v := range element {
+		if _, ok := nestedElement[k]; !ok { // Do not overwrite redeclared fields
+			nestedElement[k] = v
+		}
+	}
+
+	return &schema.Resource{
+		CreateContext: resourceDatasetCollectionCreate,
+		ReadContext:   resourceDatasetCollectionRead,
+		UpdateContext: resourceDatasetCollectionUpdate,
+		DeleteContext: resourceDatasetCollectionDelete,
+		Schema: map[string]*schema.Schema{
+			//"id": {
+			//	Type:     schema.TypeString,
+			//	Computed: true,
+			//},
+			"history_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of history to create collection in",
+			},
+			"collection_type": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+				ValidateDiagFunc: func(v interface{}, path cty.Path) diag.Diagnostics {
+					switch v.(string) {
+					default:
+						diags := diag.Errorf("invalid collection type %s", v)
+						diags[0].AttributePath = path
+						return diags
+					case "list":
+					case "paired":
+					case "list:paired":
+					}
+					return nil
+				},
+				Description: "Collection type (list, paired, list:paired)",
+			},
+			"element": {
+				Type:     schema.TypeList,
+				Required: true,
+				ForceNew: true,
+				Elem: &schema.Resource{
+					Schema: nestedElement,
+				},
+				Description: "Repeatable block of collection elements, in order",
+			},
+			"copy_elements": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				ForceNew:    true,
+				Description: "Copy element datasets into the target history rather than referencing the originals",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Collection name as displayed to user",
+			},
+			"tags": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "List of tags assigned to collection",
+			},
+			"hid": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Index of collection within history",
+			},
+			"element_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Count of elements in collection",
+			},
+			"populated_state": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Populated state of collection",
+			},
+			"url": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "API url of collection",
+			},
+			"deleted": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Deleted",
+			},
+		},
+		Importer:    &schema.ResourceImporter{StateContext: schema.ImportStatePassthroughContext},
+		Description: "Dataset collections (HDCAs) group datasets within a history into lists or pairs. The collection id can be passed to galaxy_job hdca inputs or workflow invocations.",
+	}
+}
+
+// Get a HDCA by id
+func getHDCA(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID) (*histories.HistoryDatasetCollectionAssociation, error) {
+	// GET /api/dataset_collections/{id}
+	if res, err := g.R(ctx).SetResult(&histories.HistoryDatasetCollectionAssociation{}).SetQueryParam("instance_type", "history").Get(path.Join("/api/dataset_collections", id)); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			return result.(*histories.HistoryDatasetCollectionAssociation), nil
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+func hdcaToSchema(hdca *histories.HistoryDatasetCollectionAssociation, d *schema.ResourceData) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for k, v := range map[string]interface{}{
+		"history_id":      hdca.HistoryId,
+		"collection_type": hdca.CollectionType,
+		"name":            hdca.Name,
+		"tags":            hdca.Tags,
+		"hid":             hdca.Hid,
+		"element_count":   hdca.ElementCount,
+		"populated_state": hdca.PopulatedState,
+		"url":             hdca.Url,
+		"deleted":         hdca.Deleted,
+	} {
+		if err := d.Set(k, v); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	d.SetId(hdca.Id)
+	return diags
+}
+
+// Convert element blocks to the element_identifiers payload of the collection creation API
+func elementIdentifiers(elements []interface{}, collectionType string) ([]map[string]interface{}, error) {
+	types := strings.SplitN(collectionType, ":", 2)
+	var identifiers []map[string]interface{}
+	for _, e := range elements {
+		element := e.(map[string]interface{})
+		identifier := map[string]interface{}{
+			"name": element["identifier"].(string),
+		}
+		if len(types) > 1 {
+			// Nested collection, build sub-collection from nested element blocks
+			sub, ok := element["element"].([]interface{})
+			if !ok || len(sub) == 0 {
+				return nil, fmt.Errorf("element %v requires nested element blocks for collection type %v", identifier["name"], collectionType)
+			}
+			subIdentifiers, err := elementIdentifiers(sub, types[1])
+			if err != nil {
+				return nil, err
+			}
+			identifier["src"] = "new_collection"
+			identifier["collection_type"] = types[1]
+			identifier["element_identifiers"] = subIdentifiers
+		} else {
+			if id, ok := element["id"].(string); !ok || id == "" {
+				return nil, fmt.Errorf("element %v requires a dataset id", identifier["name"])
+			}
+			identifier["id"] = element["id"].(string)
+			identifier["src"] = element["src"].(string)
+		}
+		identifiers = append(identifiers, identifier)
+	}
+	if types[0] == "paired" {
+		if len(identifiers) != 2 || identifiers[0]["name"] != "forward" || identifiers[1]["name"] != "reverse" {
+			return nil, fmt.Errorf("paired collections require exactly two elements, 'forward' followed by 'reverse'")
+		}
+	}
+	return identifiers, nil
+}
+
+func resourceDatasetCollectionCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	collectionType := d.Get("collection_type").(string)
+	identifiers, err := elementIdentifiers(d.Get("element").([]interface{}), collectionType)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	body := map[string]interface{}{
+		"type":                "dataset_collection",
+		"collection_type":     collectionType,
+		"element_identifiers": identifiers,
+		"copy_elements":       d.Get("copy_elements").(bool),
+	}
+	if name, ok := d.GetOk("name"); ok {
+		body["name"] = name.(string)
+	}
+
+	// POST /api/histories/{history_id}/contents
+	if res, err := g.R(ctx).SetResult(&histories.HistoryDatasetCollectionAssociation{}).SetBody(body).Post(path.Join(histories.BasePath, d.Get("history_id").(string), "contents")); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			hdca := result.(*histories.HistoryDatasetCollectionAssociation)
+			d.SetId(hdca.Id)
+			if _, ok := d.GetOk("tags"); ok {
+				return resourceDatasetCollectionUpdate(ctx, d, m)
+			}
+			return hdcaToSchema(hdca, d)
+		} else {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+}
+
+func resourceDatasetCollectionRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	if hdca, err := getHDCA(ctx, g, d.Id()); err == nil {
+		return hdcaToSchema(hdca, d)
+	} else {
+		return diag.FromErr(err)
+	}
+}
+
+func resourceDatasetCollectionUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	tags := []string{}
+	for _, tag := range d.Get("tags").([]interface{}) {
+		tags = append(tags, tag.(string))
+	}
+	body := map[string]interface{}{
+		"name": d.Get("name").(string),
+		"tags": tags,
+	}
+
+	// PUT /api/histories/{history_id}/contents/dataset_collections/{id}
+	if res, err := g.R(ctx).SetBody(body).Put(path.Join(histories.BasePath, d.Get("history_id").(string), "contents", "dataset_collections", d.Id())); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	return resourceDatasetCollectionRead(ctx, d, m)
+}
+
+func resourceDatasetCollectionDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	// DELETE /api/histories/{history_id}/contents/dataset_collections/{id}
+	if res, err := g.R(ctx).Delete(path.Join(histories.BasePath, d.Get("history_id").(string), "contents", "dataset_collections", d.Id())); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package galaxy_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/histories"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"path"
+	"testing"
+)
+
+const DatasetCollectionResourcePath = "test-fixtures/dataset_collection.tf"
+
+func testAccDatasetCollectionExists(resourceName string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return fmt.Errorf("Not found: %s", resourceName)
+		}
+
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("ID unset")
+		}
+
+		if res, err := testAccGalaxyInstance().R(context.Background()).SetResult(&histories.HistoryDatasetCollectionAssociation{}).Get(path.Join("/api/dataset_collections", rs.Primary.ID)); err == nil {
+			if result, err := blend4go.HandleResponse(res); err == nil {
+				if hdca := result.(*histories.HistoryDatasetCollectionAssociation); hdca.Id != rs.Primary.ID {
+					return fmt.Errorf("ID mismatch between stored ID (%v) and fetched (%v)", rs.Primary.ID, hdca.Id)
+				}
+			} else {
+				return err
+			}
+		} else {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func TestAccDatasetCollection_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(DatasetCollectionResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_dataset_collection." + name
+	type tmplFields struct {
+		Name           string
+		CollectionName string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		IDRefreshName:     resourceName,
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, CollectionName: "pair"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccDatasetCollectionExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "name", "pair"),
+					resource.TestCheckResourceAttr(resourceName, "collection_type", "paired"),
+					resource.TestCheckResourceAttr(resourceName, "element_count", "2"),
+				),
+			},
+		},
+	})
+}
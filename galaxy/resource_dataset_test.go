@@ -0,0 +1,68 @@
+package galaxy_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/histories"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"path"
+	"testing"
+)
+
+const DatasetResourcePath = "test-fixtures/dataset.tf"
+
+func testAccDatasetExists(resourceName string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return fmt.Errorf("Not found: %s", resourceName)
+		}
+
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("ID unset")
+		}
+
+		if res, err := testAccGalaxyInstance().R(context.Background()).SetResult(&histories.HistoryDatasetAssociation{}).Get(path.Join("/api/datasets", rs.Primary.ID)); err == nil {
+			if result, err := blend4go.HandleResponse(res); err == nil {
+				if hda := result.(*histories.HistoryDatasetAssociation); hda.Id != rs.Primary.ID {
+					return fmt.Errorf("ID mismatch between stored ID (%v) and fetched (%v)", rs.Primary.ID, hda.Id)
+				}
+			} else {
+				return err
+			}
+		} else {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func TestAccDataset_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(DatasetResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_dataset." + name
+	type tmplFields struct {
+		Name        string
+		DatasetName string
+		Content     string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		IDRefreshName:     resourceName,
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, DatasetName: "test.txt", Content: "foo"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccDatasetExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "name", "test.txt"),
+					resource.TestCheckResourceAttr(resourceName, "state", "ok"),
+					resource.TestCheckResourceAttrSet(resourceName, "hid"),
+				),
+			},
+		},
+	})
+}
@@ -2,14 +2,31 @@ package galaxy
 
 import (
 	"context"
+	"errors"
 	"github.com/brinkmanlab/blend4go"
 	"github.com/brinkmanlab/blend4go/histories"
+	"github.com/brinkmanlab/blend4go/jobs"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
 var historyOmitFields = map[string]interface{}{"model_class": nil, "state_ids": nil}
 
+// Fields managed through the sharing endpoints rather than PUT /api/histories/{id}
+var historySharingFields = map[string]interface{}{"importable": nil, "published": nil}
+
+// Dataset permission actions as named by the history permissions form
+var historyPermissionActions = map[string]string{
+	"default_access_roles": "DATASET_ACCESS",
+	"default_manage_roles": "DATASET_MANAGE_PERMISSIONS",
+}
+
 func resourceHistory() *schema.Resource {
 	return &schema.Resource{
 		CreateContext: resourceHistoryCreate,
@@ -23,8 +40,9 @@ func resourceHistory() *schema.Resource {
 			//},
 			"importable": {
 				Type:        schema.TypeBool,
+				Optional:    true,
 				Computed:    true,
-				Description: "Importable",
+				Description: "Allow users to import history via link",
 			},
 			"create_time": {
 				Type:        schema.TypeString,
@@ -105,7 +123,8 @@ func resourceHistory() *schema.Resource {
 			"name": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				Description: "History name as displayed to user",
+				Computed:    true,
+				Description: "History name as displayed to user. Imported histories keep the name stored in the archive if unset.",
 			},
 			"url": {
 				Type:        schema.TypeString,
@@ -125,7 +144,34 @@ func resourceHistory() *schema.Resource {
 			"published": {
 				Type:        schema.TypeBool,
 				Optional:    true,
-				Description: "Published",
+				Computed:    true,
+				Description: "Make history available to all users",
+			},
+			"shared_with_users": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Set of user ids or email addresses to share history with",
+			},
+			"default_access_roles": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Set of role ids permitted to access new datasets in history",
+			},
+			"default_manage_roles": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Set of role ids permitted to manage permissions of new datasets in history",
 			},
 			//"model_class": &schema.Schema{
 			//	Type:     schema.TypeString,
@@ -142,17 +188,289 @@ func resourceHistory() *schema.Resource {
 				Default:     true,
 				Description: "Purge history on delete",
 			},
+			"source_url": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"source_archive"},
+				Description:   "URL of history archive to import history from. See galaxy_history_export.",
+			},
+			"source_archive": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"source_url"},
+				Description:   "Path to local history archive to import history from. The file is read and uploaded by the provider. See galaxy_history_export.",
+			},
 		},
 		Importer:    &schema.ResourceImporter{StateContext: schema.ImportStatePassthroughContext},
 		Description: "Galaxy histories organise and group data into 'workspaces'. All datasets must be associated with a history, including job outputs.",
 	}
 }
 
+// Response of GET /api/histories/{id}/sharing and GET /api/workflows/{id}/sharing
+type sharingStatus struct {
+	Importable      bool   `json:"importable"`
+	Published       bool   `json:"published"`
+	UsernameAndSlug string `json:"username_and_slug"`
+	UsersSharedWith []struct {
+		Id    blend4go.GalaxyID `json:"id"`
+		Email string            `json:"email"`
+	} `json:"users_shared_with"`
+}
+
+// Response of GET /history/permissions
+type historyPermissionsForm struct {
+	Inputs []struct {
+		Name  string              `json:"name"`
+		Value []blend4go.GalaxyID `json:"value"`
+	} `json:"inputs"`
+}
+
+// Apply changes to sharing and default permissions of history
+func historySharingFromSchema(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) diag.Diagnostics {
+	var diags diag.Diagnostics
+	base := path.Join(histories.BasePath, d.Id())
+	put := func(endpoint string, body interface{}) {
+		r := g.R(ctx)
+		if body != nil {
+			r.SetBody(body)
+		}
+		if res, err := r.Put(path.Join(base, endpoint)); err == nil {
+			if _, err := blend4go.HandleResponse(res); err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		} else {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+
+	if d.HasChange("importable") {
+		// PUT /api/histories/{id}/enable_link_access
+		// PUT /api/histories/{id}/disable_link_access
+		if d.Get("importable").(bool) {
+			put("enable_link_access", nil)
+		} else {
+			put("disable_link_access", nil)
+		}
+	}
+	if d.HasChange("published") {
+		// PUT /api/histories/{id}/publish
+		// PUT /api/histories/{id}/unpublish
+		if d.Get("published").(bool) {
+			put("publish", nil)
+		} else {
+			put("unpublish", nil)
+		}
+	}
+	if d.HasChange("shared_with_users") {
+		// PUT /api/histories/{id}/share_with_users
+		put("share_with_users", map[string]interface{}{"user_ids": d.Get("shared_with_users").(*schema.Set).List()})
+	}
+	if d.HasChanges("default_access_roles", "default_manage_roles") {
+		// PUT /history/permissions
+		body := map[string]interface{}{}
+		for attr, action := range historyPermissionActions {
+			body[action] = d.Get(attr).(*schema.Set).List()
+		}
+		if res, err := g.R(ctx).SetQueryParam("id", d.Id()).SetBody(body).Put("/history/permissions"); err == nil {
+			if _, err := blend4go.HandleResponse(res); err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		} else {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	return diags
+}
+
+// Populate sharing and default permissions of history
+func historySharingToSchema(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// GET /api/histories/{id}/sharing
+	if res, err := g.R(ctx).SetResult(&sharingStatus{}).Get(path.Join(histories.BasePath, d.Id(), "sharing")); err == nil {
+		if endpointUnsupported(res.StatusCode(), res.Header().Get("Content-Type")) {
+			// importable and published are still reported by the history itself
+			if _, ok := d.GetOk("shared_with_users"); ok {
+				diags = append(diags, unsupportedWarning("GET /api/histories/{id}/sharing", "shared_with_users")...)
+			}
+		} else if result, err := blend4go.HandleResponse(res); err == nil {
+			status := result.(*sharingStatus)
+			// Report users in the same form that they were configured, by email or id
+			configured := d.Get("shared_with_users").(*schema.Set)
+			var users []string
+			for _, user := range status.UsersSharedWith {
+				if configured.Contains(user.Email) {
+					users = append(users, user.Email)
+				} else {
+					users = append(users, user.Id)
+				}
+			}
+			for k, v := range map[string]interface{}{
+				"importable":        status.Importable,
+				"published":         status.Published,
+				"username_and_slug": status.UsernameAndSlug,
+				"shared_with_users": users,
+			} {
+				if err := d.Set(k, v); err != nil {
+					diags = append(diags, diag.FromErr(err)...)
+				}
+			}
+		} else {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	} else {
+		diags = append(diags, diag.FromErr(err)...)
+	}
+
+	// GET /history/permissions
+	if res, err := g.R(ctx).SetResult(&historyPermissionsForm{}).SetQueryParam("id", d.Id()).Get("/history/permissions"); err == nil {
+		if endpointUnsupported(res.StatusCode(), res.Header().Get("Content-Type")) {
+			_, hasAccess := d.GetOk("default_access_roles")
+			_, hasManage := d.GetOk("default_manage_roles")
+			if hasAccess || hasManage {
+				diags = append(diags, unsupportedWarning("GET /history/permissions", "default_access_roles", "default_manage_roles")...)
+			}
+		} else if result, err := blend4go.HandleResponse(res); err == nil {
+			form := result.(*historyPermissionsForm)
+			for attr, action := range historyPermissionActions {
+				roles := []blend4go.GalaxyID{}
+				for _, input := range form.Inputs {
+					if input.Name == action {
+						roles = input.Value
+					}
+				}
+				if err := d.Set(attr, roles); err != nil {
+					diags = append(diags, diag.FromErr(err)...)
+				}
+			}
+		} else {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	} else {
+		diags = append(diags, diag.FromErr(err)...)
+	}
+
+	return diags
+}
+
+// Ids of the histories of the current user
+func historyIDs(ctx context.Context, g *blend4go.GalaxyInstance) (map[blend4go.GalaxyID]bool, error) {
+	// GET /api/histories
+	if res, err := g.R(ctx).SetResult(&[]*historyOwner{}).SetQueryParam("keys", "id").Get(histories.BasePath); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			ids := map[blend4go.GalaxyID]bool{}
+			for _, history := range *result.(*[]*historyOwner) {
+				ids[history.Id] = true
+			}
+			return ids, nil
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Import a history from an archive, returning the new history
+func importHistory(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) (*histories.History, error) {
+	// The import API does not return the new history, record the existing histories to find it once imported
+	existing, err := historyIDs(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+
+	// POST /api/histories
+	r := g.R(ctx).SetResult(&jobs.Job{})
+	if source, ok := d.GetOk("source_url"); ok {
+		r.SetBody(map[string]string{"archive_source": source.(string), "archive_type": "url"})
+	} else {
+		source := d.Get("source_archive").(string)
+		// Archives can be several GB, stream the file rather than reading it into memory
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r.SetFormData(map[string]string{"archive_source": "", "archive_type": "file"}).SetFileReader("archive_file", filepath.Base(source), file)
+	}
+	job := new(jobs.Job)
+	if res, err := r.Post(histories.BasePath); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			job = result.(*jobs.Job)
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	// Wait for import job to complete
+	for !jobEnded[job.State] {
+		time.Sleep(2 * time.Second)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var err error
+		if job, err = jobs.Get(ctx, g, job.Id); err != nil {
+			return nil, err
+		}
+	}
+	if job.State != "ok" {
+		return nil, errors.New("history import failed, see " + g.Client.HostURL + "/api/jobs/" + job.Id + " for more info")
+	}
+
+	// Galaxy versions that associate the import job with the new history identify it directly
+	if job.HistoryId != "" && !existing[job.HistoryId] {
+		return histories.Get(ctx, g, job.HistoryId)
+	}
+
+	// Otherwise find the histories created since the import started, refusing to guess between several
+	current, err := historyIDs(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for id := range current {
+		if !existing[id] {
+			ids = append(ids, id)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return nil, errors.New("history imported by job " + job.Id + " not found")
+	case 1:
+		return histories.Get(ctx, g, ids[0])
+	}
+	sort.Strings(ids)
+	return nil, errors.New("unable to identify history imported by job " + job.Id + ", histories " + strings.Join(ids, ", ") + " were created while it ran. Import the intended history with terraform import.")
+}
+
 func resourceHistoryCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	g := m.(*blend4go.GalaxyInstance)
 
+	_, hasURL := d.GetOk("source_url")
+	_, hasArchive := d.GetOk("source_archive")
+	if hasURL || hasArchive {
+		if history, err := importHistory(ctx, g, d); err == nil {
+			d.SetId(history.GetID())
+			if _, ok := d.GetOk("name"); !ok {
+				if err := d.Set("name", history.Name); err != nil {
+					return diag.FromErr(err)
+				}
+			}
+			return resourceHistoryUpdate(ctx, d, m)
+		} else {
+			return diag.FromErr(err)
+		}
+	}
+
 	if history, err := histories.NewHistory(ctx, g, d.Get("name").(string)); err == nil {
-		return toSchema(history, d, historyOmitFields)
+		d.SetId(history.GetID())
+		diags := historySharingFromSchema(ctx, g, d)
+		diags = append(diags, toSchema(history, d, historyOmitFields)...)
+		return append(diags, historySharingToSchema(ctx, g, d)...)
 	} else {
 		return diag.FromErr(err)
 	}
@@ -162,7 +480,8 @@ func resourceHistoryRead(ctx context.Context, d *schema.ResourceData, m interfac
 	g := m.(*blend4go.GalaxyInstance)
 
 	if history, err := histories.Get(ctx, g, d.Id()); err == nil {
-		return toSchema(history, d, historyOmitFields)
+		diags := toSchema(history, d, historyOmitFields)
+		return append(diags, historySharingToSchema(ctx, g, d)...)
 	} else {
 		return diag.FromErr(err)
 	}
@@ -174,11 +493,13 @@ func resourceHistoryUpdate(ctx context.Context, d *schema.ResourceData, m interf
 	history := new(histories.History)
 	history.SetGalaxyInstance(g)
 	var diags diag.Diagnostics
-	diags = append(diags, fromSchema(history, d, nil)...)
+	diags = append(diags, fromSchema(history, d, &historySharingFields)...)
 	if err := history.Update(ctx); err != nil {
 		diags = append(diags, diag.FromErr(err)...)
 	}
+	diags = append(diags, historySharingFromSchema(ctx, g, d)...)
 	diags = append(diags, toSchema(history, d, historyOmitFields)...)
+	diags = append(diags, historySharingToSchema(ctx, g, d)...)
 	return diags
 }
 
@@ -0,0 +1,277 @@
+package galaxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/histories"
+	"github.com/brinkmanlab/blend4go/jobs"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+func resourceHistoryExport() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceHistoryExportCreate,
+		ReadContext:   resourceHistoryExportRead,
+		DeleteContext: resourceHistoryExportDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(time.Hour),
+		},
+		Schema: map[string]*schema.Schema{
+			//"id": {
+			//	Type:     schema.TypeString,
+			//	Computed: true,
+			//},
+			"history_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of history to export",
+			},
+			"format": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "tar.gz",
+				ForceNew: true,
+				ValidateDiagFunc: func(v interface{}, path cty.Path) diag.Diagnostics {
+					switch v.(string) {
+					default:
+						diags := diag.Errorf("invalid export format %s", v)
+						diags[0].AttributePath = path
+						return diags
+					case "tar.gz":
+					case "rocrate.zip":
+					}
+					return nil
+				},
+				Description: "Archive format (tar.gz, rocrate.zip)",
+			},
+			"include_hidden": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				ForceNew:    true,
+				Description: "Include hidden datasets in export",
+			},
+			"include_deleted": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				ForceNew:    true,
+				Description: "Include deleted datasets in export",
+			},
+			"path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Local path to write archive to. The file is removed when the resource is destroyed.",
+			},
+			"triggers": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				ForceNew: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Arbitrary map of values that, when changed, will trigger a new export",
+			},
+			"download_url": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "URL to download archive from. Archives in rocrate.zip format are only available for a short time.",
+			},
+			"checksum": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "SHA256 checksum of archive",
+			},
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Size of archive in bytes",
+			},
+		},
+		Description: "Export a history to an archive that can be downloaded or imported into another Galaxy instance using the galaxy_history source_url or source_archive arguments. Waiting for the export times out after an hour by default, configurable with a timeouts block.",
+	}
+}
+
+// Response of PUT /api/histories/{id}/exports
+type historyExportStatus struct {
+	DownloadUrl string            `json:"download_url"`
+	JobId       blend4go.GalaxyID `json:"job_id"`
+}
+
+// Response of POST /api/histories/{id}/prepare_store_download
+type storageRequest struct {
+	StorageRequestId string `json:"storage_request_id"`
+}
+
+// Export history using the legacy export API, returning the download path
+func exportHistoryArchive(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) (string, error) {
+	params := map[string]string{
+		"gzip":            "true",
+		"include_hidden":  strconv.FormatBool(d.Get("include_hidden").(bool)),
+		"include_deleted": strconv.FormatBool(d.Get("include_deleted").(bool)),
+		// Galaxy otherwise returns an existing up to date export, the resource is only created when new or replaced
+		"force": "true",
+	}
+	// PUT /api/histories/{id}/exports returns 202 until the export is ready
+	for {
+		if res, err := g.R(ctx).SetResult(&historyExportStatus{}).SetQueryParams(params).Put(path.Join(histories.BasePath, d.Get("history_id").(string), "exports")); err == nil {
+			if result, err := blend4go.HandleResponse(res); err == nil {
+				status := result.(*historyExportStatus)
+				if res.StatusCode() == http.StatusOK && status.DownloadUrl != "" {
+					d.SetId(path.Base(status.DownloadUrl))
+					return status.DownloadUrl, nil
+				}
+				// A failed export job is otherwise reported as pending indefinitely
+				if status.JobId != "" {
+					if job, err := jobs.Get(ctx, g, status.JobId); err == nil {
+						if jobEnded[job.State] && job.State != "ok" {
+							return "", errors.New("history export failed, see " + g.Client.HostURL + "/api/jobs/" + job.Id + " for more info")
+						}
+					} else {
+						return "", err
+					}
+				}
+			} else {
+				return "", err
+			}
+		} else {
+			return "", err
+		}
+		// Poll the export started by the first request
+		delete(params, "force")
+		time.Sleep(2 * time.Second)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+	}
+}
+
+// Export history using the model store API, returning the download path
+func exportHistoryStore(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) (string, error) {
+	body := map[string]interface{}{
+		"model_store_format": d.Get("format").(string),
+		"include_files":      true,
+		"include_hidden":     d.Get("include_hidden").(bool),
+		"include_deleted":    d.Get("include_deleted").(bool),
+	}
+	// POST /api/histories/{id}/prepare_store_download
+	if res, err := g.R(ctx).SetResult(&storageRequest{}).SetBody(body).Post(path.Join(histories.BasePath, d.Get("history_id").(string), "prepare_store_download")); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			id := result.(*storageRequest).StorageRequestId
+			d.SetId(id)
+			// GET /api/short_term_storage/{id}/ready
+			for ready := false; !ready; {
+				time.Sleep(2 * time.Second)
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
+				if res, err := g.R(ctx).SetResult(&ready).Get(path.Join("/api/short_term_storage", id, "ready")); err == nil {
+					if _, err := blend4go.HandleResponse(res); err != nil {
+						return "", err
+					}
+				} else {
+					return "", err
+				}
+			}
+			return path.Join("/api/short_term_storage", id), nil
+		} else {
+			return "", err
+		}
+	} else {
+		return "", err
+	}
+}
+
+func resourceHistoryExportCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	var downloadPath string
+	var err error
+	if d.Get("format").(string) == "tar.gz" {
+		downloadPath, err = exportHistoryArchive(ctx, g, d)
+	} else {
+		downloadPath, err = exportHistoryStore(ctx, g, d)
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Download archive to calculate checksum and optionally write to disk
+	if res, err := g.R(ctx).SetDoNotParseResponse(true).Get(downloadPath); err == nil {
+		body := res.RawBody()
+		defer body.Close()
+		if res.IsError() {
+			return diag.Errorf("failed to download history archive: %v %v", downloadPath, res.Status())
+		}
+		hash := sha256.New()
+		out := io.Writer(hash)
+		if p, ok := d.GetOk("path"); ok {
+			file, err := os.Create(p.(string))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			defer file.Close()
+			out = io.MultiWriter(file, hash)
+		}
+		size, err := io.Copy(out, body)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		var diags diag.Diagnostics
+		for k, v := range map[string]interface{}{
+			"download_url": g.Client.HostURL + downloadPath,
+			"checksum":     hex.EncodeToString(hash.Sum(nil)),
+			"size":         int(size),
+		} {
+			if err := d.Set(k, v); err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		}
+		return diags
+	} else {
+		return diag.FromErr(err)
+	}
+}
+
+func resourceHistoryExportRead(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	// Recreate the export if the local archive was removed or modified
+	if p, ok := d.GetOk("path"); ok {
+		if file, err := os.Open(p.(string)); err == nil {
+			defer file.Close()
+			hash := sha256.New()
+			if _, err := io.Copy(hash, file); err != nil {
+				return diag.FromErr(err)
+			}
+			if hex.EncodeToString(hash.Sum(nil)) != d.Get("checksum").(string) {
+				d.SetId("")
+			}
+		} else if os.IsNotExist(err) {
+			d.SetId("")
+		} else {
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
+
+func resourceHistoryExportDelete(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+	if p, ok := d.GetOk("path"); ok {
+		if err := os.Remove(p.(string)); err != nil && !os.IsNotExist(err) {
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
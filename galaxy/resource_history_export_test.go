@@ -0,0 +1,32 @@
+package galaxy_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"testing"
+)
+
+const HistoryExportResourcePath = "test-fixtures/history_export.tf"
+
+func TestAccHistoryExport_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(HistoryExportResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_history_export." + name
+	type tmplFields struct {
+		Name string
+		Path string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Path: t.TempDir() + "/history.tar.gz"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet(resourceName, "download_url"),
+					resource.TestCheckResourceAttrSet(resourceName, "checksum"),
+					resource.TestCheckResourceAttr("galaxy_history.imported", "name", "imported"),
+				),
+			},
+		},
+	})
+}
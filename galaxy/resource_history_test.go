@@ -52,6 +52,8 @@ func TestAccHistory_basic(t *testing.T) {
 				Check: resource.ComposeAggregateTestCheckFunc(
 					testAccHistoryExists(resourceName),
 					resource.TestCheckResourceAttr(resourceName, "name", "test"),
+					resource.TestCheckResourceAttr(resourceName, "importable", "true"),
+					resource.TestCheckResourceAttr(resourceName, "shared_with_users.#", "0"),
 					//testCheckResourceAttrEqual(resourceName, "deleted", false),
 					//testCheckResourceAttrEqual(resourceName, "purged", false),
 				),
@@ -109,7 +109,7 @@ func resourceQuota() *schema.Resource {
 				Type:        schema.TypeBool,
 				Optional:    true,
 				Default:     true,
-				Description: "Purge a user on deletion",
+				Description: "Purge quota on deletion, removing its association with users and groups",
 			},
 		},
 		Importer:    &schema.ResourceImporter{StateContext: schema.ImportStatePassthroughContext},
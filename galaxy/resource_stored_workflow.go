@@ -2,31 +2,367 @@ package galaxy
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/repositories"
+	"github.com/brinkmanlab/blend4go/tools"
 	"github.com/brinkmanlab/blend4go/workflows"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-var workflowOmitFields = map[string]interface{}{"inputs": nil, "steps": nil, "model_class": nil}
+var workflowOmitFields = map[string]interface{}{"inputs": nil, "steps": nil, "model_class": nil, "published": nil, "tags": nil}
+
+// Workflow fields that change on export without changing the meaning of the workflow
+var workflowVolatileFields = map[string]interface{}{"uuid": nil, "position": nil, "version": nil}
+
+// Strip volatile fields from a decoded workflow, its steps, step outputs and subworkflows, decoding embedded tool state.
+// Fields nested deeper, such as within tool state, are retained as tools may define parameters of the same name.
+func normaliseWorkflowData(data map[string]interface{}) {
+	for k := range workflowVolatileFields {
+		delete(data, k)
+	}
+	for _, step := range workflowSteps(data) {
+		for k := range workflowVolatileFields {
+			delete(step, k)
+		}
+		if state, ok := step["tool_state"].(string); ok {
+			// Tool state is a json encoded string within the workflow json
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(state), &decoded); err == nil {
+				step["tool_state"] = decoded
+			}
+		}
+		if outputs, ok := step["workflow_outputs"].([]interface{}); ok {
+			for _, output := range outputs {
+				if output, ok := output.(map[string]interface{}); ok {
+					for k := range workflowVolatileFields {
+						delete(output, k)
+					}
+				}
+			}
+		}
+		// Native workflows embed subworkflows in subworkflow, gxformat2 in run
+		for _, key := range []string{"subworkflow", "run"} {
+			if subworkflow, ok := step[key].(map[string]interface{}); ok {
+				normaliseWorkflowData(subworkflow)
+			}
+		}
+	}
+}
+
+// Decode workflow content in either native (.ga) json or gxformat2 yaml format
+func decodeWorkflow(workflow string) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(workflow), &data); err != nil {
+		// gxformat2 workflows are typically yaml encoded
+		data = make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(workflow), &data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// gxformat2 workflows are identified by their class
+func isFormat2(data map[string]interface{}) bool {
+	return data["class"] == "GalaxyWorkflow"
+}
+
+// Convert workflow content to json as accepted by the Galaxy API. Galaxy converts gxformat2 workflows to its native format.
+func workflowJSON(workflow string) (string, error) {
+	data, err := decodeWorkflow(workflow)
+	if err != nil {
+		return "", err
+	}
+	if !isFormat2(data) {
+		return workflow, nil
+	}
+	if j, err := json.Marshal(data); err == nil {
+		return string(j), nil
+	} else {
+		return "", err
+	}
+}
+
+// Steps of a decoded workflow keyed on index. Native workflows store steps in a map, gxformat2 workflows can use a list or map.
+func workflowSteps(data map[string]interface{}) map[string]map[string]interface{} {
+	steps := make(map[string]map[string]interface{})
+	switch s := data["steps"].(type) {
+	case map[string]interface{}:
+		for index, step := range s {
+			if step, ok := step.(map[string]interface{}); ok {
+				steps[index] = step
+			}
+		}
+	case []interface{}:
+		for index, step := range s {
+			if step, ok := step.(map[string]interface{}); ok {
+				steps[strconv.Itoa(index)] = step
+			}
+		}
+	}
+	return steps
+}
+
+// Tool shed repository referenced by a workflow
+type workflowRepository struct {
+	*repositories.Repository
+	// Versions of the tools of the repository referenced by the workflow, keyed on tool id
+	Tools map[string]string
+}
+
+// Recursively search a decoded workflow and its subworkflows for tool shed repositories, collecting them into set keyed on repository id
+func findWorkflowRepositories(data map[string]interface{}, set map[string]*workflowRepository) {
+	for _, step := range workflowSteps(data) {
+		// Native workflows embed subworkflows in subworkflow, gxformat2 in run
+		for _, key := range []string{"subworkflow", "run"} {
+			if subworkflow, ok := step[key].(map[string]interface{}); ok {
+				findWorkflowRepositories(subworkflow, set)
+			}
+		}
+		if r, ok := step["tool_shed_repository"].(map[string]interface{}); ok {
+			repo := &repositories.Repository{}
+			repo.Name, _ = r["name"].(string)
+			repo.ToolShed, _ = r["tool_shed"].(string)
+			repo.Owner, _ = r["owner"].(string)
+			repo.ChangesetRevision, _ = r["changeset_revision"].(string)
+			repo.SetID(path.Join(repo.ToolShed, repo.Owner, repo.Name, repo.ChangesetRevision))
+			if _, ok := set[repo.GetID()]; !ok {
+				set[repo.GetID()] = &workflowRepository{Repository: repo, Tools: make(map[string]string)}
+			}
+			if toolID, ok := step["tool_id"].(string); ok && toolID != "" {
+				set[repo.GetID()].Tools[toolID], _ = step["tool_version"].(string)
+			}
+		}
+	}
+}
+
+// List the unique tool shed repositories referenced by a workflow in either native or gxformat2 format, including subworkflows
+func workflowRepositories(workflow string) ([]*workflowRepository, error) {
+	data, err := decodeWorkflow(workflow)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]*workflowRepository)
+	findWorkflowRepositories(data, set)
+	repos := make([]*workflowRepository, 0, len(set))
+	for _, repo := range set {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].GetID() < repos[j].GetID() })
+	return repos, nil
+}
+
+// Set of repositories installed in the Galaxy instance, keyed on {tool_shed}/{owner}/{name}/{changeset_revision}
+func installedRepositories(ctx context.Context, g *blend4go.GalaxyInstance) (map[string]bool, error) {
+	repos, err := repositories.List(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	installed := make(map[string]bool)
+	for _, repo := range repos {
+		if !repo.Deleted && !repo.Uninstalled {
+			installed[path.Join(repo.ToolShed, repo.Owner, repo.Name, repo.ChangesetRevision)] = true
+			installed[path.Join(repo.ToolShed, repo.Owner, repo.Name, repo.InstalledChangesetRevision)] = true
+		}
+	}
+	return installed, nil
+}
+
+// Decode and normalise workflow content
+func normaliseWorkflow(workflow string) (map[string]interface{}, error) {
+	data, err := decodeWorkflow(workflow)
+	if err != nil {
+		return nil, err
+	}
+	normaliseWorkflowData(data)
+	return data, nil
+}
+
+// Hash workflow content, ignoring formatting, key order, and volatile fields
+func HashWorkflow(workflow string) string {
+	if data, err := normaliseWorkflow(workflow); err == nil {
+		if canonical, err := json.Marshal(data); err == nil { // Map keys are marshalled in sorted order
+			return HashString(string(canonical))
+		}
+	}
+	return HashString(workflow)
+}
+
+// Hash each step of the workflow keyed on step label, or index and tool id if unlabelled
+func hashWorkflowSteps(workflow string) (map[string]string, error) {
+	data, err := normaliseWorkflow(workflow)
+	if err != nil {
+		return nil, err
+	}
+	// gxformat2 steps can be keyed on label
+	_, keyed := data["steps"].(map[string]interface{})
+	keyed = keyed && isFormat2(data)
+	hashes := map[string]string{}
+	for index, step := range workflowSteps(data) {
+		key := index
+		if keyed {
+			// Step key is already the label
+		} else if label, ok := step["label"].(string); ok && label != "" {
+			key = label
+		} else if label, ok := step["id"].(string); ok && label != "" && isFormat2(data) {
+			key = label
+		} else if toolID, ok := step["tool_id"].(string); ok && toolID != "" {
+			key = fmt.Sprintf("%v: %v", index, toolID)
+		} else if t, ok := step["type"].(string); ok {
+			key = fmt.Sprintf("%v: %v", index, t)
+		}
+		canonical, err := json.Marshal(step)
+		if err != nil {
+			return nil, err
+		}
+		hashes[key] = HashString(string(canonical))
+	}
+	return hashes, nil
+}
 
 func resourceStoredWorkflow() *schema.Resource {
 	return &schema.Resource{
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceStoredWorkflowV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceStoredWorkflowStateUpgradeV0,
+			},
+		},
 		CreateContext: resourceStoredWorkflowCreate,
 		ReadContext:   resourceStoredWorkflowRead,
 		UpdateContext: resourceStoredWorkflowUpdate,
 		DeleteContext: resourceStoredWorkflowDelete,
+		CustomizeDiff: resourceStoredWorkflowCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			//"id": {
 			//	Type:     schema.TypeString,
 			//	Computed: true,
 			//},
 			"json": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"json", "source"},
+				StateFunc:    func(v interface{}) string { return HashWorkflow(v.(string)) },
+				Description:  "JSON encoded workflow (.ga) or gxformat2 YAML workflow, the format is detected automatically. See terraform file() to load a workflow file. Formatting, key order, uuids, step positions and version are ignored when detecting changes. Changes are stored as a new version of the workflow.",
+			},
+			"source": {
+				Type:         schema.TypeList,
+				Optional:     true,
+				MaxItems:     1,
+				ExactlyOneOf: []string{"json", "source"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"url": {
+							Type:          schema.TypeString,
+							Optional:      true,
+							ConflictsWith: []string{"source.0.trs_id"},
+							Description:   "URL to fetch workflow content from",
+						},
+						"trs_server": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "https://dockstore.org/api/ga4gh/trs/v2",
+							Description: "Base URL of GA4GH TRS v2 API to resolve trs_id against, such as Dockstore or https://workflowhub.eu/ga4gh/trs/v2",
+						},
+						"trs_id": {
+							Type:          schema.TypeString,
+							Optional:      true,
+							ConflictsWith: []string{"source.0.url"},
+							Description:   "TRS tool id of workflow, ie. #workflow/github.com/iwc-workflows/sars-cov-2-variation-reporting/COVID-19-VARIATION-REPORTING",
+						},
+						"trs_version": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "TRS version id of workflow. Defaults to the last version listed by the registry.",
+						},
+					},
+				},
+				Description: "Fetch workflow content from a TRS registry or URL rather than providing json. Upstream changes are detected when planning and stored as a new version of the workflow.",
+			},
+			"source_version": {
 				Type:        schema.TypeString,
-				Required:    true,
-				StateFunc:   func(v interface{}) string { return HashString(v.(string)) },
-				ForceNew:    true, // https://github.com/galaxyproject/galaxy/issues/10687
-				Description: "JSON encoded workflow. See terraform file() to load a .ga file.",
+				Computed:    true,
+				Description: "TRS version id resolved from source",
+			},
+			"source_checksum": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "SHA256 checksum of workflow content fetched from source",
+			},
+			"require_tools": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Fail planning if tools referenced by the workflow are not installed. Implies check_tools.",
+			},
+			"check_tools": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Check that tools referenced by the workflow are installed, reporting missing tools in missing_tools and as a warning. The check queries Galaxy for each tool when planning and refreshing.",
+			},
+			"pending_repositories": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"tool_shed": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Toolshed hostname",
+						},
+						"owner": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Repository owner",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Repository name",
+						},
+					},
+				},
+				Description: "Repositories that galaxy_repository resources in the same plan will install. Their tools are not reported as missing. Referencing the galaxy_repository resources here also orders their installation before the workflow. Terraform does not expose other resources in the configuration to the provider, so the repositories can not be derived and must be listed.",
+			},
+			"missing_tools": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "List of tool ids referenced by the workflow that are not installed. Empty unless check_tools or require_tools is set.",
+			},
+			"replace_on_change": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Replace the stored workflow when json changes rather than storing a new version. Required for Galaxy versions affected by https://github.com/galaxyproject/galaxy/issues/10687",
+			},
+			"step_hashes": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Map of hashes of each workflow step keyed on step label, or step index and tool id if unlabelled. Changes to this map in a plan summarise the steps added, removed or changed.",
 			},
 			"name": {
 				Type:        schema.TypeString,
@@ -38,7 +374,6 @@ func resourceStoredWorkflow() *schema.Resource {
 				Type:     schema.TypeList,
 				Optional: true,
 				Computed: true,
-				ForceNew: true, // TODO https://github.com/galaxyproject/galaxy/issues/10684
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
@@ -74,9 +409,27 @@ func resourceStoredWorkflow() *schema.Resource {
 				Type:        schema.TypeBool,
 				Optional:    true,
 				Default:     false,
-				ForceNew:    true, // TODO https://github.com/galaxyproject/galaxy/issues/10682
 				Description: "Make workflow available to all users",
 			},
+			"shared_with_users": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Set of user ids or email addresses to share workflow with",
+			},
+			"slug": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Slug of workflow used in its published and shared URLs",
+			},
+			"username_and_slug": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Username and slug, the path of the published URL of the workflow relative to the Galaxy host",
+			},
 			"owner": {
 				Type:        schema.TypeString,
 				Computed:    true,
@@ -86,10 +439,40 @@ func resourceStoredWorkflow() *schema.Resource {
 			//	Type:     schema.TypeString,
 			//	Computed: true,
 			//},
-			//"inputs": {
-			//	Type:     schema.TypeList,
-			//	Computed: true,
-			//},
+			"inputs": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"label": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Input label, used to key galaxy_workflow_invocation inputs",
+						},
+						"step": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Index of input step",
+						},
+						"type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Input type (data, data_collection, or the parameter type of parameter inputs)",
+						},
+						"collection_type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Collection type of data_collection inputs",
+						},
+						"optional": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Input is optional",
+						},
+					},
+				},
+				Description: "List of workflow inputs, ordered by step index",
+			},
 			"annotation": {
 				Type:        schema.TypeString,
 				Optional:    true,
@@ -101,10 +484,88 @@ func resourceStoredWorkflow() *schema.Resource {
 				Computed:    true,
 				Description: "Workflow version",
 			},
-			//"steps": {
-			//	Type:     schema.TypeList,
-			//	Computed: true,
-			//},
+			"version_history": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"version": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Workflow version",
+						},
+						"update_time": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Time version was stored",
+						},
+						"steps": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Count of steps in version",
+						},
+					},
+				},
+				Description: "List of stored versions of the workflow, oldest first. Empty for Galaxy versions that do not list workflow versions.",
+			},
+			"steps": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"index": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Index of step within workflow",
+						},
+						"type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Step type (tool, subworkflow, data_input, data_collection_input, parameter_input, pause)",
+						},
+						"label": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Step label",
+						},
+						"tool_id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Id of tool executed by step",
+						},
+						"tool_version": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Version of tool executed by step",
+						},
+						"connections": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"input_name": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Name of step input",
+									},
+									"source_step": {
+										Type:        schema.TypeInt,
+										Computed:    true,
+										Description: "Index of step providing the input",
+									},
+									"output_name": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Name of output of source step",
+									},
+								},
+							},
+							Description: "List of connections to outputs of other steps",
+						},
+					},
+				},
+				Description: "List of workflow steps, ordered by step index",
+			},
 			"import_tools": {
 				Type:        schema.TypeBool,
 				Optional:    true,
@@ -116,7 +577,6 @@ func resourceStoredWorkflow() *schema.Resource {
 				Type:        schema.TypeBool,
 				Optional:    true,
 				Default:     false,
-				ForceNew:    true, // TODO https://github.com/galaxyproject/galaxy/issues/10683
 				Description: "Allow users to import workflow",
 			},
 		},
@@ -125,13 +585,602 @@ func resourceStoredWorkflow() *schema.Resource {
 	}
 }
 
+// Entry of response of GET /api/workflows/{id}/versions
+type workflowVersion struct {
+	Version    uint   `json:"version"`
+	UpdateTime string `json:"update_time"`
+	Steps      uint   `json:"steps"`
+}
+
+func workflowVersionsToSchema(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) diag.Diagnostics {
+	// GET /api/workflows/{id}/versions
+	if res, err := g.R(ctx).SetResult(&[]*workflowVersion{}).Get(path.Join(workflows.BasePath, d.Id(), "versions")); err == nil {
+		if endpointUnsupported(res.StatusCode(), res.Header().Get("Content-Type")) {
+			// Galaxy versions prior to 20.09 do not list workflow versions, version_history is left empty
+			return nil
+		}
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			versions := *result.(*[]*workflowVersion)
+			sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+			var history []map[string]interface{}
+			for _, version := range versions {
+				history = append(history, map[string]interface{}{
+					"version":     version.Version,
+					"update_time": version.UpdateTime,
+					"steps":       version.Steps,
+				})
+			}
+			if err := d.Set("version_history", history); err != nil {
+				return diag.FromErr(err)
+			}
+			return nil
+		} else {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+}
+
+// GA4GH TRS v2 ToolVersion
+type trsToolVersion struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// GA4GH TRS v2 FileWrapper
+type trsFileWrapper struct {
+	Content string `json:"content"`
+}
+
+// Fetch and decode a json response from a URL outside of the Galaxy API
+func getJSON(ctx context.Context, u string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return fmt.Errorf("failed to fetch %v: %v", u, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// Fetch workflow content from the source block, returning the content and resolved TRS version
+func fetchWorkflowSource(ctx context.Context, source map[string]interface{}) (string, string, error) {
+	if u, ok := source["url"].(string); ok && u != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return "", "", err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", "", err
+		}
+		defer res.Body.Close()
+		if res.StatusCode >= 400 {
+			return "", "", fmt.Errorf("failed to fetch workflow %v: %v", u, res.Status)
+		}
+		content, err := ioutil.ReadAll(res.Body)
+		return string(content), "", err
+	}
+
+	id, ok := source["trs_id"].(string)
+	if !ok || id == "" {
+		return "", "", errors.New("source requires url or trs_id")
+	}
+	server := strings.TrimSuffix(source["trs_server"].(string), "/")
+	tool := server + "/tools/" + url.PathEscape(id)
+	version, _ := source["trs_version"].(string)
+	if version == "" {
+		// GET {trs_server}/tools/{id}/versions
+		var versions []*trsToolVersion
+		if err := getJSON(ctx, tool+"/versions", &versions); err != nil {
+			return "", "", err
+		}
+		if len(versions) == 0 {
+			return "", "", fmt.Errorf("no versions of %v available from %v", id, server)
+		}
+		version = versions[len(versions)-1].Name
+		if version == "" {
+			version = versions[len(versions)-1].Id
+		}
+	}
+	// GET {trs_server}/tools/{id}/versions/{version_id}/GALAXY/descriptor
+	descriptor := &trsFileWrapper{}
+	if err := getJSON(ctx, tool+"/versions/"+url.PathEscape(version)+"/GALAXY/descriptor", descriptor); err != nil {
+		return "", "", err
+	}
+	return descriptor.Content, version, nil
+}
+
+// Apply changes to sharing and tags of stored workflow. Set created for a workflow that was just created with its publication status.
+func workflowSharingFromSchema(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData, created bool) diag.Diagnostics {
+	var diags diag.Diagnostics
+	base := path.Join(workflows.BasePath, d.Id())
+	put := func(endpoint string, body interface{}) {
+		r := g.R(ctx)
+		if body != nil {
+			r.SetBody(body)
+		}
+		if res, err := r.Put(path.Join(base, endpoint)); err == nil {
+			if _, err := blend4go.HandleResponse(res); err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		} else {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+
+	if d.HasChange("slug") {
+		if slug, ok := d.GetOk("slug"); ok {
+			// PUT /api/workflows/{id}/slug
+			put("slug", map[string]string{"new_slug": slug.(string)})
+		}
+	}
+	// Newly created workflows are already published and importable as configured
+	if d.HasChange("importable") && !created {
+		// PUT /api/workflows/{id}/enable_link_access
+		// PUT /api/workflows/{id}/disable_link_access
+		if d.Get("importable").(bool) {
+			put("enable_link_access", nil)
+		} else {
+			put("disable_link_access", nil)
+		}
+	}
+	if d.HasChange("published") && !created {
+		// PUT /api/workflows/{id}/publish
+		// PUT /api/workflows/{id}/unpublish
+		if d.Get("published").(bool) {
+			put("publish", nil)
+		} else {
+			put("unpublish", nil)
+		}
+	}
+	if d.HasChange("shared_with_users") {
+		// PUT /api/workflows/{id}/share_with_users
+		put("share_with_users", map[string]interface{}{"user_ids": d.Get("shared_with_users").(*schema.Set).List()})
+	}
+	if d.HasChange("tags") {
+		// PUT /api/tags
+		body := map[string]interface{}{
+			"item_id":    d.Id(),
+			"item_class": "StoredWorkflow",
+			"item_tags":  d.Get("tags").([]interface{}),
+		}
+		if res, err := g.R(ctx).SetBody(body).Put("/api/tags"); err == nil {
+			if _, err := blend4go.HandleResponse(res); err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		} else {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	return diags
+}
+
+// Populate sharing of stored workflow
+func workflowSharingToSchema(ctx context.Context, g *blend4go.GalaxyInstance, workflow *workflows.StoredWorkflow, d *schema.ResourceData) diag.Diagnostics {
+	var diags diag.Diagnostics
+	status, err := getWorkflowSharing(ctx, g, d.Id())
+	if errors.Is(err, errEndpointUnsupported) {
+		// Publication and tags are still reported by the workflow itself, the remaining attributes keep their prior values
+		for k, v := range map[string]interface{}{
+			"published": workflow.Published,
+			"tags":      workflow.Tags,
+		} {
+			if err := d.Set(k, v); err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		}
+		_, hasSlug := d.GetOk("slug")
+		_, hasUsers := d.GetOk("shared_with_users")
+		if hasSlug || hasUsers {
+			diags = append(diags, unsupportedWarning("GET /api/workflows/{id}/sharing", "importable", "slug", "shared_with_users")...)
+		}
+		return diags
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+	// Report users in the same form that they were configured, by email or id
+	configured := d.Get("shared_with_users").(*schema.Set)
+	var users []string
+	for _, user := range status.UsersSharedWith {
+		if configured.Contains(user.Email) {
+			users = append(users, user.Email)
+		} else {
+			users = append(users, user.Id)
+		}
+	}
+	var slug string
+	if status.UsernameAndSlug != "" {
+		slug = path.Base(status.UsernameAndSlug)
+	}
+	for k, v := range map[string]interface{}{
+		"importable":        status.Importable,
+		"published":         status.Published,
+		"slug":              slug,
+		"username_and_slug": status.UsernameAndSlug,
+		"shared_with_users": users,
+		"tags":              workflow.Tags,
+	} {
+		if err := d.Set(k, v); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	return diags
+}
+
+// Tool ids and versions referenced by a decoded workflow, including subworkflows
+func workflowTools(data map[string]interface{}) map[string]string {
+	tools := make(map[string]string)
+	for _, step := range workflowSteps(data) {
+		// Native workflows embed subworkflows in subworkflow, gxformat2 in run
+		for _, key := range []string{"subworkflow", "run"} {
+			if subworkflow, ok := step[key].(map[string]interface{}); ok {
+				for toolID, version := range workflowTools(subworkflow) {
+					tools[toolID] = version
+				}
+			}
+		}
+		if toolID, ok := step["tool_id"].(string); ok && toolID != "" {
+			tools[toolID], _ = step["tool_version"].(string)
+		}
+	}
+	return tools
+}
+
+// Repository of a tool shed tool guid ({tool_shed}/repos/{owner}/{name}/{tool_id}/{version}) as {tool_shed}/{owner}/{name}
+func toolRepository(toolID string) (string, bool) {
+	parts := strings.Split(toolID, "/")
+	if len(parts) >= 4 && parts[1] == "repos" {
+		return path.Join(parts[0], parts[2], parts[3]), true
+	}
+	return "", false
+}
+
+// Check if a tool version is installed
+func toolInstalled(ctx context.Context, g *blend4go.GalaxyInstance, toolID, version string) (bool, error) {
+	// GET /api/tools/{id}
+	req := g.R(ctx).SetResult(&tools.Tool{})
+	if version != "" {
+		req.SetQueryParam("tool_version", version)
+	}
+	if res, err := req.Get(path.Join(tools.BasePath, toolID)); err == nil {
+		if res.StatusCode() == http.StatusNotFound {
+			return false, nil
+		}
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			return version == "" || result.(*tools.Tool).Version == version, nil
+		} else {
+			return false, err
+		}
+	} else {
+		return false, err
+	}
+}
+
+// List tools referenced by a workflow that are not installed, ignoring tools of pending repositories keyed on {tool_shed}/{owner}/{name}
+func missingWorkflowTools(ctx context.Context, g *blend4go.GalaxyInstance, workflow string, pending map[string]bool) ([]string, error) {
+	data, err := decodeWorkflow(workflow)
+	if err != nil {
+		return nil, err
+	}
+	required, err := workflowRepositories(workflow)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := installedRepositories(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	missingRepos := make(map[string]bool)
+	for _, repo := range required {
+		if !installed[repo.GetID()] {
+			missingRepos[path.Join(repo.ToolShed, repo.Owner, repo.Name)] = true
+		}
+	}
+
+	missing := make([]string, 0)
+	for toolID, version := range workflowTools(data) {
+		if repo, ok := toolRepository(toolID); ok {
+			if pending[repo] {
+				continue
+			}
+			if missingRepos[repo] {
+				missing = append(missing, toolID)
+				continue
+			}
+		}
+		if ok, err := toolInstalled(ctx, g, toolID, version); err == nil {
+			if !ok {
+				missing = append(missing, toolID)
+			}
+		} else {
+			return nil, err
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// Tools are only checked if requested as the check queries Galaxy for each tool
+func checkWorkflowTools(d interface{ Get(string) interface{} }) bool {
+	return d.Get("check_tools").(bool) || d.Get("require_tools").(bool)
+}
+
+// Warn of tools referenced by the workflow that are not installed
+func missingToolsWarning(missing []string) diag.Diagnostics {
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "workflow references tools that are not installed",
+		Detail:   strings.Join(missing, ", "),
+	}}
+}
+
+// Flatten the steps and inputs of the latest version of the stored workflow, and report missing tools
+func workflowStepsToSchema(ctx context.Context, g *blend4go.GalaxyInstance, workflow *workflows.StoredWorkflow, d *schema.ResourceData) diag.Diagnostics {
+	content, err := workflow.Download(ctx)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	var diags diag.Diagnostics
+	missing := make([]string, 0)
+	if checkWorkflowTools(d) {
+		if missing, err = missingWorkflowTools(ctx, g, content, nil); err != nil {
+			return diag.FromErr(err)
+		}
+		if len(missing) > 0 {
+			diags = append(diags, missingToolsWarning(missing)...)
+		}
+	}
+	data, err := decodeWorkflow(content)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if len(d.Get("step_hashes").(map[string]interface{})) == 0 {
+		// Imported workflows have no configured json to hash
+		if hashes, err := hashWorkflowSteps(content); err == nil {
+			if err := d.Set("step_hashes", hashes); err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		} else {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+
+	workflowSteps := workflowSteps(data)
+	indices := make([]int, 0, len(workflowSteps))
+	for index := range workflowSteps {
+		if i, err := strconv.Atoi(index); err == nil {
+			indices = append(indices, i)
+		}
+	}
+	sort.Ints(indices)
+
+	steps := make([]map[string]interface{}, 0, len(indices))
+	inputs := make([]map[string]interface{}, 0)
+	for _, index := range indices {
+		step := workflowSteps[strconv.Itoa(index)]
+		stepType, _ := step["type"].(string)
+		label, _ := step["label"].(string)
+		toolID, _ := step["tool_id"].(string)
+		toolVersion, _ := step["tool_version"].(string)
+
+		connections := make([]map[string]interface{}, 0)
+		if inputConnections, ok := step["input_connections"].(map[string]interface{}); ok {
+			names := make([]string, 0, len(inputConnections))
+			for name := range inputConnections {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				// Connections are either a single connection or a list of connections
+				sources, ok := inputConnections[name].([]interface{})
+				if !ok {
+					sources = []interface{}{inputConnections[name]}
+				}
+				for _, s := range sources {
+					if source, ok := s.(map[string]interface{}); ok {
+						sourceStep, _ := source["id"].(float64)
+						outputName, _ := source["output_name"].(string)
+						connections = append(connections, map[string]interface{}{
+							"input_name":  name,
+							"source_step": int(sourceStep),
+							"output_name": outputName,
+						})
+					}
+				}
+			}
+		}
+
+		steps = append(steps, map[string]interface{}{
+			"index":        index,
+			"type":         stepType,
+			"label":        label,
+			"tool_id":      toolID,
+			"tool_version": toolVersion,
+			"connections":  connections,
+		})
+
+		if strings.HasSuffix(stepType, "_input") {
+			// Input configuration is stored in the json encoded tool state
+			state := make(map[string]interface{})
+			if toolState, ok := step["tool_state"].(string); ok {
+				_ = json.Unmarshal([]byte(toolState), &state)
+			}
+			inputType := strings.TrimSuffix(stepType, "_input")
+			if parameterType, ok := state["parameter_type"].(string); ok && stepType == "parameter_input" {
+				inputType = parameterType
+			}
+			collectionType, _ := state["collection_type"].(string)
+			optional, _ := state["optional"].(bool)
+			inputs = append(inputs, map[string]interface{}{
+				"label":           label,
+				"step":            index,
+				"type":            inputType,
+				"collection_type": collectionType,
+				"optional":        optional,
+			})
+		}
+	}
+
+	for k, v := range map[string]interface{}{
+		"steps":         steps,
+		"inputs":        inputs,
+		"missing_tools": missing,
+	} {
+		if err := d.Set(k, v); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	return diags
+}
+
+func resourceStoredWorkflowV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"json":                 {Type: schema.TypeString, Required: true},
+			"name":                 {Type: schema.TypeString, Optional: true, Computed: true},
+			"tags":                 {Type: schema.TypeList, Optional: true, Computed: true, Elem: &schema.Schema{Type: schema.TypeString}},
+			"deleted":              {Type: schema.TypeBool, Computed: true},
+			"latest_workflow_uuid": {Type: schema.TypeString, Computed: true},
+			"show_in_tool_panel":   {Type: schema.TypeBool, Optional: true, Computed: true},
+			"url":                  {Type: schema.TypeString, Computed: true},
+			"number_of_steps":      {Type: schema.TypeInt, Computed: true},
+			"published":            {Type: schema.TypeBool, Optional: true},
+			"owner":                {Type: schema.TypeString, Computed: true},
+			"annotation":           {Type: schema.TypeString, Optional: true, Computed: true},
+			"version":              {Type: schema.TypeInt, Computed: true},
+			"import_tools":         {Type: schema.TypeBool, Optional: true},
+			"importable":           {Type: schema.TypeBool, Optional: true},
+		},
+	}
+}
+
+// Replace the hash of the raw json stored in state with the normalised hash of the workflow held by Galaxy.
+// The configured json is not available to the upgrade, the downloaded workflow hashes the same unless its content differs.
+func resourceStoredWorkflowStateUpgradeV0(ctx context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	id, ok := rawState["id"].(string)
+	if !ok || id == "" {
+		return rawState, nil
+	}
+	g := meta.(*blend4go.GalaxyInstance)
+	workflow, err := workflows.Get(ctx, g, id)
+	if err != nil {
+		return nil, err
+	}
+	content, err := workflow.Download(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rawState["json"] = HashWorkflow(content)
+	return rawState, nil
+}
+
+func resourceStoredWorkflowCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	g := m.(*blend4go.GalaxyInstance)
+
+	if source, ok := d.Get("source").([]interface{}); ok && len(source) > 0 && source[0] != nil {
+		// Resolve workflow content from source, triggering a change to json if the content changed upstream
+		content, version, err := fetchWorkflowSource(ctx, source[0].(map[string]interface{}))
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256([]byte(content))
+		checksum := hex.EncodeToString(sum[:])
+		if d.Id() == "" || d.HasChange("source") || checksum != d.Get("source_checksum").(string) {
+			for k, v := range map[string]interface{}{
+				"json":            content,
+				"source_version":  version,
+				"source_checksum": checksum,
+			} {
+				if err := d.SetNew(k, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if d.HasChange("json") {
+		if d.Id() != "" {
+			if d.Get("replace_on_change").(bool) {
+				if err := d.ForceNew("json"); err != nil {
+					return err
+				}
+			} else {
+				// A new version will be stored
+				for _, k := range []string{"version", "version_history", "latest_workflow_uuid", "number_of_steps", "steps", "inputs"} {
+					if err := d.SetNewComputed(k); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if !d.NewValueKnown("json") {
+			// Workflow content generated by other resources is only known when applying
+			for _, k := range []string{"step_hashes", "missing_tools"} {
+				if err := d.SetNewComputed(k); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		// Summarise changes to the workflow steps in the plan
+		if hashes, err := hashWorkflowSteps(d.Get("json").(string)); err == nil {
+			if err := d.SetNew("step_hashes", hashes); err != nil {
+				return err
+			}
+		} else {
+			return err
+		}
+
+		// Check that the tools referenced by the workflow are installed
+		if !checkWorkflowTools(d) {
+			return nil
+		}
+		pending := make(map[string]bool)
+		for _, r := range d.Get("pending_repositories").(*schema.Set).List() {
+			repo := r.(map[string]interface{})
+			pending[path.Join(repo["tool_shed"].(string), repo["owner"].(string), repo["name"].(string))] = true
+		}
+		if missing, err := missingWorkflowTools(ctx, g, d.Get("json").(string), pending); err == nil {
+			if len(missing) > 0 && d.Get("require_tools").(bool) {
+				return fmt.Errorf("workflow references tools that are not installed: %v", strings.Join(missing, ", "))
+			}
+			return d.SetNew("missing_tools", missing)
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
 func resourceStoredWorkflowCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	g := m.(*blend4go.GalaxyInstance)
 
-	json := d.Get("json").(string)
+	j := d.Get("json").(string)
+	content, err := workflowJSON(j)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 
-	if workflow, err := workflows.NewStoredWorkflow(ctx, g, json, d.Get("import_tools").(bool), d.Get("published").(bool), d.Get("importable").(bool)); err == nil {
-		return toSchema(workflow, d, workflowOmitFields)
+	if workflow, err := workflows.NewStoredWorkflow(ctx, g, content, d.Get("import_tools").(bool), d.Get("published").(bool), d.Get("importable").(bool)); err == nil {
+		d.SetId(workflow.GetID())
+		var diags diag.Diagnostics
+		if hashes, err := hashWorkflowSteps(j); err == nil {
+			if err := d.Set("step_hashes", hashes); err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		} else {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+		diags = append(diags, workflowSharingFromSchema(ctx, g, d, true)...)
+		return append(diags, resourceStoredWorkflowRead(ctx, d, m)...)
 	} else {
 		return diag.FromErr(err)
 	}
@@ -141,7 +1190,10 @@ func resourceStoredWorkflowRead(ctx context.Context, d *schema.ResourceData, m i
 	g := m.(*blend4go.GalaxyInstance)
 
 	if workflow, err := workflows.Get(ctx, g, d.Id()); err == nil {
-		return toSchema(workflow, d, workflowOmitFields)
+		diags := toSchema(workflow, d, workflowOmitFields)
+		diags = append(diags, workflowSharingToSchema(ctx, g, workflow, d)...)
+		diags = append(diags, workflowStepsToSchema(ctx, g, workflow, d)...)
+		return append(diags, workflowVersionsToSchema(ctx, g, d)...)
 	} else {
 		return diag.FromErr(err)
 	}
@@ -150,23 +1202,39 @@ func resourceStoredWorkflowRead(ctx context.Context, d *schema.ResourceData, m i
 func resourceStoredWorkflowUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	g := m.(*blend4go.GalaxyInstance)
 
-	var j string
+	body := map[string]interface{}{"menu_entry": "False"}
+	if d.Get("show_in_tool_panel").(bool) {
+		body["menu_entry"] = "True"
+	}
 	if d.HasChange("json") {
-		j = d.Get("json").(string)
+		j, err := workflowJSON(d.Get("json").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		workflow := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(j), &workflow); err != nil {
+			return diag.FromErr(err)
+		}
+		body["workflow"] = workflow
+	}
+	// Galaxy takes the name and annotation of a new version from its json unless they are overridden, only send them if they changed
+	for _, k := range []string{"name", "annotation"} {
+		if v, ok := d.GetOk(k); ok && d.HasChange(k) {
+			body[k] = v
+		}
 	}
 
-	workflow := new(workflows.StoredWorkflow)
-	workflow.SetGalaxyInstance(g)
-	workflow.SetID(d.Id())
-	workflow.Name = d.Get("name").(string)
-	workflow.Annotation = d.Get("annotation").(string)
-	workflow.ShowInToolPanel = d.Get("show_in_tool_panel").(bool)
-
-	if err := workflow.Update(ctx, j); err != nil {
-		return diag.FromErr(err)
+	// Workflow json is stored as a new version, retaining the stored workflow id and sharing links
+	// PUT /api/workflows/{id}
+	if res, err := g.R(ctx).SetBody(body).Put(path.Join(workflows.BasePath, d.Id())); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return diag.FromErr(err)
+		}
 	} else {
-		return nil
+		return diag.FromErr(err)
 	}
+	diags := workflowSharingFromSchema(ctx, g, d, false)
+	return append(diags, resourceStoredWorkflowRead(ctx, d, m)...)
 }
 
 func resourceStoredWorkflowDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -174,7 +1242,7 @@ func resourceStoredWorkflowDelete(ctx context.Context, d *schema.ResourceData, m
 	g := m.(*blend4go.GalaxyInstance)
 	workflow := new(workflows.StoredWorkflow)
 	workflow.SetGalaxyInstance(g)
-	diags = append(diags, fromSchema(workflow, d, nil)...)
+	diags = append(diags, fromSchema(workflow, d, &workflowOmitFields)...)
 	if err := workflow.Delete(ctx); err != nil {
 		diags = append(diags, diag.FromErr(err)...)
 	}
@@ -8,12 +8,19 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
 	"terraform-provider-galaxy/galaxy"
 	"testing"
 )
 
 const WorkflowPath = "./test-fixtures/workflow.ga"
+const WorkflowFormat2Path = "./test-fixtures/workflow.gxwf.yml"
 const WorkflowResourcePath = "./test-fixtures/workflow.tf"
+const WorkflowSourceResourcePath = "./test-fixtures/workflow_source.tf"
+const WorkflowRequireToolsResourcePath = "./test-fixtures/workflow_require_tools.tf"
+const WorkflowSharingResourcePath = "./test-fixtures/workflow_sharing.tf"
 
 func testAccWorkflowExists(resourceName string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
@@ -50,12 +57,58 @@ func loadWorkflow(path string) (string, map[string]interface{}, error) {
 	}
 }
 
+func TestHashWorkflow(t *testing.T) {
+	workflow, _, err := loadWorkflow(WorkflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := func(f func(step map[string]interface{})) string {
+		_, parsed, err := loadWorkflow(WorkflowPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed["uuid"] = "00000000-0000-0000-0000-000000000000"
+		f(parsed["steps"].(map[string]interface{})["0"].(map[string]interface{}))
+		j, err := json.Marshal(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(j)
+	}
+
+	moved := modified(func(step map[string]interface{}) {
+		step["position"] = map[string]interface{}{"left": 0, "top": 0}
+	})
+	if galaxy.HashWorkflow(moved) != galaxy.HashWorkflow(workflow) {
+		t.Error("moving a step changed the workflow hash")
+	}
+	// Tool parameters that share a name with volatile fields are significant
+	parameter := modified(func(step map[string]interface{}) {
+		step["tool_state"] = `{"position": "1"}`
+	})
+	changed := modified(func(step map[string]interface{}) {
+		step["tool_state"] = `{"position": "2"}`
+	})
+	if galaxy.HashWorkflow(parameter) == galaxy.HashWorkflow(changed) {
+		t.Error("changing a tool parameter named position did not change the workflow hash")
+	}
+}
+
 func TestAccWorkflow_basic(t *testing.T) {
 	tmpl := testAccConfigTemplate(WorkflowResourcePath, t)
 	name := "test"
 	resourceName := "galaxy_stored_workflow." + name
 	workflow, parsedWorkflow, err := loadWorkflow(WorkflowPath)
-	hash := galaxy.HashString(workflow)
+	hash := galaxy.HashWorkflow(workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, updated, err := loadWorkflow(WorkflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated["annotation"] = "updated"
+	updatedWorkflow, err := json.Marshal(updated)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -76,9 +129,208 @@ func TestAccWorkflow_basic(t *testing.T) {
 					resource.TestCheckResourceAttr(resourceName, "json", hash),
 					resource.TestCheckResourceAttr(resourceName, "name", parsedWorkflow["name"].(string)),
 					resource.TestCheckResourceAttr(resourceName, "annotation", parsedWorkflow["annotation"].(string)),
+					resource.TestCheckResourceAttrSet(resourceName, "step_hashes.%"),
+					resource.TestCheckResourceAttr(resourceName, "version_history.#", "1"),
+					resource.TestCheckResourceAttr(resourceName, "steps.#", "1"),
+					resource.TestCheckResourceAttr(resourceName, "steps.0.type", "tool"),
+					resource.TestCheckResourceAttr(resourceName, "steps.0.tool_id", "toolshed.g2.bx.psu.edu/repos/brinkmanlab/awkscript/awkscript/1.0"),
+					resource.TestCheckResourceAttr(resourceName, "inputs.#", "0"),
 					//testCheckResourceAttrEquals(resourceName, "tags", parsed_workflow["tags"].([]string)),
 				),
 			},
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: string(updatedWorkflow)}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccWorkflowExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "json", galaxy.HashWorkflow(string(updatedWorkflow))),
+					resource.TestCheckResourceAttr(resourceName, "annotation", "updated"),
+					resource.TestCheckResourceAttr(resourceName, "version_history.#", "2"),
+				),
+			},
+			{
+				ResourceName: resourceName,
+				ImportState:  true,
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 || states[0].Attributes["step_hashes.%"] != "1" {
+						return fmt.Errorf("expected step_hashes of imported workflow")
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccWorkflow_format2(t *testing.T) {
+	tmpl := testAccConfigTemplate(WorkflowResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_stored_workflow." + name
+	workflow, err := ioutil.ReadFile(WorkflowFormat2Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type tmplFields struct {
+		Name string
+		Json string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		IDRefreshName:     resourceName,
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: string(workflow)}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccWorkflowExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "json", galaxy.HashWorkflow(string(workflow))),
+					resource.TestCheckResourceAttr(resourceName, "name", "Test format2 workflow"),
+					resource.TestCheckResourceAttr(resourceName, "number_of_steps", "1"),
+					resource.TestCheckResourceAttrSet(resourceName, "step_hashes.awkscript"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccWorkflow_source(t *testing.T) {
+	tmpl := testAccConfigTemplate(WorkflowSourceResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_stored_workflow." + name
+	trsId := "#workflow/github.com/brinkmanlab/test"
+	workflow, parsedWorkflow, err := loadWorkflow(WorkflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedWorkflow["annotation"] = "updated"
+	updatedWorkflow, err := json.Marshal(parsedWorkflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stub TRS registry
+	content := workflow
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tools/"+trsId+"/versions", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]map[string]string{{"id": "v1", "name": "v1"}})
+	})
+	mux.HandleFunc("/tools/"+trsId+"/versions/v1/GALAXY/descriptor", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"content": content})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	type tmplFields struct {
+		Name      string
+		TrsServer string
+		TrsId     string
+	}
+	config := testAccConfig(tmpl, t, &tmplFields{Name: name, TrsServer: server.URL, TrsId: trsId})
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccWorkflowExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "json", galaxy.HashWorkflow(workflow)),
+					resource.TestCheckResourceAttr(resourceName, "source_version", "v1"),
+					resource.TestCheckResourceAttrSet(resourceName, "source_checksum"),
+					resource.TestCheckResourceAttr(resourceName, "version_history.#", "1"),
+				),
+			},
+			{
+				PreConfig: func() { content = string(updatedWorkflow) },
+				Config:    config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccWorkflowExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "json", galaxy.HashWorkflow(string(updatedWorkflow))),
+					resource.TestCheckResourceAttr(resourceName, "annotation", "updated"),
+					resource.TestCheckResourceAttr(resourceName, "version_history.#", "2"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccWorkflow_requireTools(t *testing.T) {
+	tmpl := testAccConfigTemplate(WorkflowRequireToolsResourcePath, t)
+	name := "test"
+	_, parsedWorkflow, err := loadWorkflow(WorkflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reference a tool that can not be installed
+	step := parsedWorkflow["steps"].(map[string]interface{})["0"].(map[string]interface{})
+	step["tool_id"] = "toolshed.g2.bx.psu.edu/repos/brinkmanlab/missing/missing/1.0"
+	step["tool_shed_repository"].(map[string]interface{})["name"] = "missing"
+	workflow, err := json.Marshal(parsedWorkflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type tmplFields struct {
+		Name         string
+		Json         string
+		RequireTools bool
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConfig(tmpl, t, &tmplFields{Name: name, Json: string(workflow), RequireTools: true}),
+				ExpectError: regexp.MustCompile("workflow references tools that are not installed"),
+			},
+			{
+				// Missing tools are only reported when not required
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: string(workflow), RequireTools: false}),
+				Check:  resource.TestCheckResourceAttr("galaxy_stored_workflow."+name, "missing_tools.0", "toolshed.g2.bx.psu.edu/repos/brinkmanlab/missing/missing/1.0"),
+			},
+		},
+	})
+}
+
+func TestAccWorkflow_sharing(t *testing.T) {
+	tmpl := testAccConfigTemplate(WorkflowSharingResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_stored_workflow." + name
+	workflow, _, err := loadWorkflow(WorkflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type tmplFields struct {
+		Name      string
+		Json      string
+		Published bool
+		Slug      string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: workflow, Published: false, Slug: "terraform-test"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccWorkflowExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "published", "false"),
+					resource.TestCheckResourceAttr(resourceName, "importable", "true"),
+					resource.TestCheckResourceAttr(resourceName, "slug", "terraform-test"),
+					resource.TestCheckResourceAttr(resourceName, "tags.#", "1"),
+					resource.TestCheckResourceAttr(resourceName, "shared_with_users.#", "0"),
+				),
+			},
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: workflow, Published: true, Slug: "terraform-test-published"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccWorkflowExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "published", "true"),
+					resource.TestCheckResourceAttr(resourceName, "slug", "terraform-test-published"),
+					resource.TestCheckResourceAttr(resourceName, "version_history.#", "1"),
+				),
+			},
 		},
 	})
 }
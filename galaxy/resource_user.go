@@ -2,20 +2,47 @@ package galaxy
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/histories"
 	"github.com/brinkmanlab/blend4go/users"
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 var userOmitFields = map[string]interface{}{"preferences": nil}
 
+// API base paths of the groups and roles a user can be associated with, keyed on attribute
+var userMembershipPaths = map[string]string{
+	"groups": "/api/groups",
+	"roles":  "/api/roles",
+}
+
 func resourceUser() *schema.Resource {
 	return &schema.Resource{
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceUserV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceUserStateUpgradeV0,
+			},
+		},
 		CreateContext: resourceUserCreate,
 		ReadContext:   resourceUserRead,
 		UpdateContext: resourceUserUpdate,
 		DeleteContext: resourceUserDelete,
+		CustomizeDiff: resourceUserCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			//"id": {
 			//	Type:     schema.TypeString,
@@ -27,20 +54,64 @@ func resourceUser() *schema.Resource {
 				Description: "Username to identify user",
 			},
 			"password": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Sensitive:   true,
-				Description: "Password to authenticate user against Galaxy",
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+				DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+					// State holds a hash of the password
+					return passwordMatches(old, new)
+				},
+				Description: "Password to authenticate user against Galaxy. Only a salted bcrypt hash of the password is stored in state. Changes are applied through the admin password reset, overwriting passwords changed outside of terraform. If unset, the user is created with a random password that is not stored. Leave unset for users authenticated by remote user or OIDC, or users created only for automation that authenticate with a galaxy_user_api_key.",
 			},
 			"quota_percent": {
 				Type:        schema.TypeInt,
 				Computed:    true,
 				Description: "Storage quota, between 0 and 100",
 			},
-			/*"preferences": {
-				Type:     ?,
+			"preferences": {
+				Type:     schema.TypeMap,
 				Computed: true,
-			},*/
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Map of all user preferences",
+			},
+			"manage_extra_preferences": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Manage the extra preferences of the user with extra_preferences. An unset or empty extra_preferences removes all extra preferences. Extra preferences are left unchanged when disabled.",
+			},
+			"extra_preferences": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Map of extra user information fields keyed on section|field, as configured in Galaxy's user_preferences_extra_conf.yml. These are available to job routing as the users extra preferences. Fields not configured in Galaxy are rejected. Requires manage_extra_preferences.",
+			},
+			"manage_permissions": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Manage the default permissions of new histories of the user with default_access_roles and default_manage_roles. An unset or empty set removes all roles of that permission. Permissions are left unchanged when disabled.",
+			},
+			"default_access_roles": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Set of role ids permitted to access datasets in new histories of the user. Requires manage_permissions.",
+			},
+			"default_manage_roles": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Set of role ids permitted to manage permissions of datasets in new histories of the user. Requires manage_permissions.",
+			},
 			"total_disk_usage": {
 				Type:        schema.TypeFloat,
 				Computed:    true,
@@ -88,13 +159,61 @@ func resourceUser() *schema.Resource {
 				Type:        schema.TypeString,
 				Computed:    true,
 				Sensitive:   true,
-				Description: "API key of user",
+				Description: "API key of user, only available if password is set. See galaxy_user_api_key to manage keys without a password.",
 			},
-			"purge": {
+			"recalculate_disk_usage": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Recalculate disk usage of user after purge_deleted_datasets purges datasets, before reading total_disk_usage and quota_percent. Galaxy may recalculate asynchronously, in which case the new usage is reported by the next refresh.",
+			},
+			"purge_deleted_datasets": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Purge the deleted datasets of the users histories, including deleted histories that are not purged, when the user is created, when this is enabled, and when the user is destroyed without being purged. Galaxy can not list histories by owner, the histories of all users are paged through to find them. Requires Galaxy 23.0 or later.",
+			},
+			"manage_membership": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Manage group and role membership of the user with groups and roles. An unset or empty groups or roles removes all membership of that kind. Membership is left unchanged when disabled.",
+			},
+			"groups": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Set of group ids the user is a member of. Requires manage_membership. Conflicts with managing the same membership through galaxy_quota or the admin UI. Groups the user is added to outside of Terraform are not detected.",
+			},
+			"roles": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Set of role ids associated with the user, excluding the users private role. Requires manage_membership. Roles the user is associated with outside of Terraform are not detected.",
+			},
+			"active": {
 				Type:        schema.TypeBool,
 				Optional:    true,
 				Default:     true,
-				Description: "Purge a user on deletion",
+				Description: "User account is activated. Deactivated users can not log in.",
+			},
+			"on_destroy": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ConflictsWith:    []string{"purge"},
+				ValidateDiagFunc: validateUserDestroyMode,
+				Description:      "Action taken when the resource is destroyed (deactivate, delete, purge). Defaults to purge. A deactivated user must be imported to be managed again.",
+			},
+			"purge": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				ConflictsWith: []string{"on_destroy"},
+				Deprecated:    "Use on_destroy",
+				Description:   "Purge a user on deletion, replaced by on_destroy",
 			},
 		},
 		Importer:    &schema.ResourceImporter{StateContext: schema.ImportStatePassthroughContext},
@@ -102,55 +221,616 @@ func resourceUser() *schema.Resource {
 	}
 }
 
-func handleUser(ctx context.Context, user *users.User, d *schema.ResourceData) diag.Diagnostics {
+// Add or remove the user from each group or role that was added to or removed from the schema
+func userMembershipFromSchema(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) diag.Diagnostics {
 	var diags diag.Diagnostics
-	if apiKey, err := user.GetAPIKey(ctx, d.Get("password").(string)); err == nil {
-		if err := d.Set("api_key", apiKey); err != nil {
-			diags = diag.FromErr(err)
+	for attr, basePath := range userMembershipPaths {
+		o, n := d.GetChange(attr)
+		old, new := o.(*schema.Set), n.(*schema.Set)
+		for _, id := range new.Difference(old).List() {
+			// PUT /api/{groups,roles}/{id}/users/{user_id}
+			if res, err := g.R(ctx).Put(path.Join(basePath, id.(string), "users", d.Id())); err == nil {
+				if _, err := blend4go.HandleResponse(res); err != nil {
+					diags = append(diags, diag.FromErr(err)...)
+				}
+			} else {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		}
+		for _, id := range old.Difference(new).List() {
+			// DELETE /api/{groups,roles}/{id}/users/{user_id}
+			if res, err := g.R(ctx).Delete(path.Join(basePath, id.(string), "users", d.Id())); err == nil {
+				// Removing the user from a deleted group or role has nothing left to do
+				if res.StatusCode() != http.StatusNotFound {
+					if _, err := blend4go.HandleResponse(res); err != nil {
+						diags = append(diags, diag.FromErr(err)...)
+					}
+				}
+			} else {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+		}
+	}
+	return diags
+}
+
+// Check if a user is associated with a group or role
+func userIsMember(ctx context.Context, g *blend4go.GalaxyInstance, basePath string, id, userId blend4go.GalaxyID) (bool, error) {
+	// GET /api/{groups,roles}/{id}/users
+	if res, err := g.R(ctx).SetResult(&[]*users.User{}).Get(path.Join(basePath, id, "users")); err == nil {
+		if res.StatusCode() == http.StatusNotFound {
+			return false, nil
+		}
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			for _, user := range *result.(*[]*users.User) {
+				if user.Id == userId {
+					return true, nil
+				}
+			}
+			return false, nil
+		} else {
+			return false, err
 		}
 	} else {
-		diags = diag.FromErr(err)
+		return false, err
+	}
+}
+
+// Populate group and role membership of user, if managed. Only groups and roles already in the schema are checked.
+func userMembershipToSchema(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) diag.Diagnostics {
+	if !d.Get("manage_membership").(bool) {
+		return nil
+	}
+	var diags diag.Diagnostics
+	for attr, basePath := range userMembershipPaths {
+		ids := []blend4go.GalaxyID{}
+		for _, id := range d.Get(attr).(*schema.Set).List() {
+			member, err := userIsMember(ctx, g, basePath, id.(string), d.Id())
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			if member {
+				ids = append(ids, id.(string))
+			}
+		}
+		if err := d.Set(attr, ids); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	return diags
+}
+
+// Schema of galaxy_user prior to storing a hash of the password
+func resourceUserV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"username": {Type: schema.TypeString, Required: true},
+			"password": {Type: schema.TypeString, Optional: true, Sensitive: true},
+			"email":    {Type: schema.TypeString, Required: true},
+			"api_key":  {Type: schema.TypeString, Computed: true, Sensitive: true},
+			"purge":    {Type: schema.TypeBool, Optional: true},
+		},
+	}
+}
+
+// Replace the password stored in state with its hash
+func resourceUserStateUpgradeV0(_ context.Context, rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+	if password, ok := rawState["password"].(string); ok && password != "" {
+		hash, err := hashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		rawState["password"] = hash
+	}
+	return rawState, nil
+}
+
+// Replace the configured password with its hash before the state is stored
+func setPasswordHash(d *schema.ResourceData) diag.Diagnostics {
+	password := d.Get("password").(string)
+	if password == "" {
+		return nil
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("password", hash); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceUserCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if !d.Get("manage_membership").(bool) {
+		for attr := range userMembershipPaths {
+			if d.Get(attr).(*schema.Set).Len() > 0 {
+				return fmt.Errorf("%v requires manage_membership", attr)
+			}
+		}
+	}
+	if !d.Get("manage_permissions").(bool) {
+		for attr := range historyPermissionActions {
+			if d.Get(attr).(*schema.Set).Len() > 0 {
+				return fmt.Errorf("%v requires manage_permissions", attr)
+			}
+		}
+	}
+	if !d.Get("manage_extra_preferences").(bool) && len(d.Get("extra_preferences").(map[string]interface{})) > 0 {
+		return fmt.Errorf("extra_preferences requires manage_extra_preferences")
+	}
+	return nil
+}
+
+func validateUserDestroyMode(v interface{}, path cty.Path) diag.Diagnostics {
+	switch v.(string) {
+	default:
+		diags := diag.Errorf("invalid on_destroy mode %s", v)
+		diags[0].AttributePath = path
+		return diags
+	case "deactivate":
+	case "delete":
+	case "purge":
+	}
+	return nil
+}
+
+// Populate user, fetching its API key if the password is provided.
+// State only holds a hash of the password, it is only available while applying a change.
+func handleUser(ctx context.Context, user *users.User, d *schema.ResourceData, password string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if password != "" {
+		if apiKey, err := user.GetAPIKey(ctx, password); err == nil {
+			if err := d.Set("api_key", apiKey); err != nil {
+				diags = diag.FromErr(err)
+			}
+		} else {
+			// The password may have been changed outside of terraform, the previous key is retained
+			diags = diag.Diagnostics{{
+				Severity: diag.Warning,
+				Summary:  "Unable to authenticate as user " + user.Username + " to fetch its API key",
+				Detail:   err.Error(),
+			}}
+		}
 	}
 	return append(diags, toSchema(user, d, userOmitFields)...)
 }
 
+// Generate a password for users that do not authenticate with a password
+func randomPassword() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 func resourceUserCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	g := m.(*blend4go.GalaxyInstance)
 
-	if user, err := users.NewUser(ctx, g, d.Get("username").(string), d.Get("password").(string), d.Get("email").(string)); err == nil {
-		return handleUser(ctx, user, d)
+	password := d.Get("password").(string)
+	if password == "" {
+		var err error
+		if password, err = randomPassword(); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if user, undeleted, err := createUser(ctx, g, d.Get("username").(string), password, d.Get("email").(string)); err == nil {
+		if undeleted && d.Get("password").(string) != "" {
+			// Undeleted users retain their previous password
+			if err := resetUserPassword(ctx, g, user.Id, password); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+		diags := userMembershipCreate(ctx, g, user, d)
+		return append(diags, setPasswordHash(d)...)
 	} else {
-		if err, ok := err.(*blend4go.ErrorResponse); ok {
-			if err.Code == 400008 {
-				// Attempt to undelete user
-				if res, e := users.List(ctx, g, true, "", d.Get("username").(string), ""); e == nil {
-					if len(res) == 0 {
-						return diag.FromErr(err)
-					}
-					if len(res) != 1 {
-						return diag.Errorf("unexpected number of results when searching for deleted user: %v", len(res))
-					}
-					if err := res[0].Undelete(ctx); err == nil {
-						return handleUser(ctx, res[0], d)
-					} else {
-						return diag.FromErr(err)
+		return diag.FromErr(err)
+	}
+}
+
+// Create a user, undeleting a matching deleted user if the username or email is taken.
+// Returns true if the user was undeleted, retaining its previous password.
+func createUser(ctx context.Context, g *blend4go.GalaxyInstance, username, password, email string) (*users.User, bool, error) {
+	user, err := users.NewUser(ctx, g, username, password, email)
+	if err == nil {
+		return user, false, nil
+	}
+	if e, ok := err.(*blend4go.ErrorResponse); ok && e.Code == 400008 {
+		// Attempt to undelete user
+		deleted, e := findDeletedUser(ctx, g, username, email)
+		if e != nil {
+			return nil, false, e
+		}
+		if deleted == nil {
+			return nil, false, err
+		}
+		if err := deleted.Undelete(ctx); err != nil {
+			return nil, false, err
+		}
+		return deleted, true, nil
+	}
+	return nil, false, err
+}
+
+// Response of GET /api/users/{id}, fields not modeled by users.User
+type userStatus struct {
+	Active      *bool             `json:"active"`
+	Preferences map[string]string `json:"preferences"`
+}
+
+// Decode extra preferences stored as JSON within the user preferences
+func extraPreferences(preferences map[string]string) map[string]string {
+	result := map[string]string{}
+	var extra map[string]interface{}
+	if err := json.Unmarshal([]byte(preferences["extra_user_preferences"]), &extra); err == nil {
+		for k, v := range extra {
+			if v != nil {
+				result[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	return result
+}
+
+// Save username, email, and extra preferences of user
+func saveUserInformation(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID, username, email string, extra map[string]interface{}) error {
+	body := map[string]interface{}{}
+	for k, v := range extra {
+		body[k] = v
+	}
+	body["username"] = username
+	body["email"] = email
+	// PUT /api/users/{id}/information/inputs
+	if res, err := g.R(ctx).SetBody(body).Put(path.Join(users.BasePath, id, "information", "inputs")); err == nil {
+		_, err := blend4go.HandleResponse(res)
+		return err
+	} else {
+		return err
+	}
+}
+
+// Save username, email, and extra preferences of user from the schema. Extra preferences are retained if not managed.
+func putUserInformation(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) error {
+	if !d.Get("manage_extra_preferences").(bool) {
+		return renameUser(ctx, g, d.Id(), d.Get("username").(string), d.Get("email").(string))
+	}
+	// The form replaces all extra preferences
+	return saveUserInformation(ctx, g, d.Id(), d.Get("username").(string), d.Get("email").(string), d.Get("extra_preferences").(map[string]interface{}))
+}
+
+// Change username and email of user, retaining its extra preferences
+func renameUser(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID, username, email string) error {
+	// GET /api/users/{id}
+	if res, err := g.R(ctx).SetResult(&userStatus{}).Get(path.Join(users.BasePath, id)); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			extra := map[string]interface{}{}
+			for k, v := range extraPreferences(result.(*userStatus).Preferences) {
+				extra[k] = v
+			}
+			return saveUserInformation(ctx, g, id, username, email, extra)
+		} else {
+			return err
+		}
+	} else {
+		return err
+	}
+}
+
+// Set password of user without requiring its current password
+func resetUserPassword(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID, password string) error {
+	// PUT /admin/reset_user_password
+	if res, err := g.R(ctx).SetQueryParam("id", id).SetBody(map[string]string{
+		"password": password,
+		"confirm":  password,
+	}).Put("/admin/reset_user_password"); err == nil {
+		_, err := blend4go.HandleResponse(res)
+		return err
+	} else {
+		return err
+	}
+}
+
+// Report extra preferences that Galaxy did not store as configured
+func verifyExtraPreferences(expected map[string]interface{}, d *schema.ResourceData) diag.Diagnostics {
+	var diags diag.Diagnostics
+	current := d.Get("extra_preferences").(map[string]interface{})
+	keys := map[string]bool{}
+	for k := range expected {
+		keys[k] = true
+	}
+	for k := range current {
+		keys[k] = true
+	}
+	var sorted []string
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		if _, ok := expected[k]; !ok {
+			diags = append(diags, diag.Errorf("extra preference %v of user %v was not removed", k, d.Id())...)
+		} else if current[k] != expected[k] {
+			diags = append(diags, diag.Errorf("extra preference %v of user %v was not saved, check that it is configured in Galaxy", k, d.Id())...)
+		}
+	}
+	return diags
+}
+
+// Set default permissions of new histories of user
+func userPermissionsFromSchema(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) diag.Diagnostics {
+	body := map[string]interface{}{}
+	for attr, action := range historyPermissionActions {
+		body[action] = d.Get(attr).(*schema.Set).List()
+	}
+	// PUT /api/users/{id}/permissions/inputs
+	if res, err := g.R(ctx).SetBody(body).Put(path.Join(users.BasePath, d.Id(), "permissions", "inputs")); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+// Populate default permissions of new histories of user, if managed
+func userPermissionsToSchema(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) diag.Diagnostics {
+	if !d.Get("manage_permissions").(bool) {
+		return nil
+	}
+	var diags diag.Diagnostics
+	// GET /api/users/{id}/permissions/inputs
+	if res, err := g.R(ctx).SetResult(&historyPermissionsForm{}).Get(path.Join(users.BasePath, d.Id(), "permissions", "inputs")); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			form := result.(*historyPermissionsForm)
+			for attr, action := range historyPermissionActions {
+				roles := []blend4go.GalaxyID{}
+				for _, input := range form.Inputs {
+					if input.Name == action {
+						roles = input.Value
 					}
-				} else {
-					return diag.FromErr(e)
 				}
-			} else {
+				if err := d.Set(attr, roles); err != nil {
+					diags = append(diags, diag.FromErr(err)...)
+				}
+			}
+		} else {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	} else {
+		diags = append(diags, diag.FromErr(err)...)
+	}
+	return diags
+}
+
+// Activate or deactivate user
+func setUserActive(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID, active bool) error {
+	// PUT /api/users/{id}
+	if res, err := g.R(ctx).SetBody(map[string]bool{"active": active}).Put(path.Join(users.BasePath, id)); err == nil {
+		_, err := blend4go.HandleResponse(res)
+		return err
+	} else {
+		return err
+	}
+}
+
+// Populate activation status and preferences of user
+func userStatusToSchema(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) diag.Diagnostics {
+	// GET /api/users/{id}
+	if res, err := g.R(ctx).SetResult(&userStatus{}).Get(path.Join(users.BasePath, d.Id())); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			status := result.(*userStatus)
+			// Galaxy versions that do not report activation status leave the configured value
+			if status.Active != nil {
+				if err := d.Set("active", *status.Active); err != nil {
+					return diag.FromErr(err)
+				}
+			}
+			if err := d.Set("preferences", status.Preferences); err != nil {
 				return diag.FromErr(err)
 			}
+			if d.Get("manage_extra_preferences").(bool) {
+				if err := d.Set("extra_preferences", extraPreferences(status.Preferences)); err != nil {
+					return diag.FromErr(err)
+				}
+			}
+		} else {
+			return diag.FromErr(err)
 		}
+	} else {
 		return diag.FromErr(err)
 	}
+	return nil
+}
+
+// Apply membership and activation status of newly created or undeleted user
+func userMembershipCreate(ctx context.Context, g *blend4go.GalaxyInstance, user *users.User, d *schema.ResourceData) diag.Diagnostics {
+	diags := handleUser(ctx, user, d, d.Get("password").(string))
+	if d.Id() == "" {
+		return diags
+	}
+	if err := setUserActive(ctx, g, d.Id(), d.Get("active").(bool)); err != nil {
+		diags = append(diags, diag.FromErr(err)...)
+	}
+	// Undeleted users may have deleted datasets remaining
+	if d.Get("purge_deleted_datasets").(bool) {
+		if err := purgeUserDatasets(ctx, g, d); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	// Undeleted users may have previous extra preferences and permissions, managed values are always applied
+	extra := d.Get("extra_preferences").(map[string]interface{})
+	manageExtra := d.Get("manage_extra_preferences").(bool)
+	if manageExtra {
+		if err := putUserInformation(ctx, g, d); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	if d.Get("manage_permissions").(bool) {
+		diags = append(diags, userPermissionsFromSchema(ctx, g, d)...)
+	}
+	diags = append(diags, userStatusToSchema(ctx, g, d)...)
+	if manageExtra {
+		diags = append(diags, verifyExtraPreferences(extra, d)...)
+	}
+	diags = append(diags, userPermissionsToSchema(ctx, g, d)...)
+	if d.Get("manage_membership").(bool) {
+		diags = append(diags, userMembershipFromSchema(ctx, g, d)...)
+	}
+	return append(diags, userMembershipToSchema(ctx, g, d)...)
+}
+
+// Find a deleted user matching both username and email. Returns nil if no deleted user matches either.
+func findDeletedUser(ctx context.Context, g *blend4go.GalaxyInstance, username, email string) (*users.User, error) {
+	// Filters match substrings, exact matches are selected below
+	byEmail, err := users.List(ctx, g, true, email, "", "")
+	if err != nil {
+		return nil, err
+	}
+	byName, err := users.List(ctx, g, true, "", username, "")
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[blend4go.GalaxyID]*users.User)
+	for _, user := range append(byEmail, byName...) {
+		if user.Email == email || user.Username == username {
+			candidates[user.Id] = user
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	if len(candidates) == 1 {
+		for _, user := range candidates {
+			if user.Email == email && user.Username == username {
+				return user, nil
+			}
+		}
+	}
+
+	// Report the conflicting fields of each candidate
+	var conflicts []string
+	for _, user := range candidates {
+		if user.Email != email {
+			conflicts = append(conflicts, fmt.Sprintf("deleted user %v matches username %v but has email %v", user.Id, username, user.Email))
+		} else if user.Username != username {
+			conflicts = append(conflicts, fmt.Sprintf("deleted user %v matches email %v but has username %v", user.Id, email, user.Username))
+		} else {
+			conflicts = append(conflicts, fmt.Sprintf("deleted user %v matches username %v and email %v", user.Id, username, email))
+		}
+	}
+	sort.Strings(conflicts)
+	return nil, fmt.Errorf("unable to select a deleted user to undelete: %v", strings.Join(conflicts, "; "))
+}
+
+// Recalculate disk usage of user
+func recalculateDiskUsage(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID) error {
+	// PUT /api/users/{id}/recalculate_disk_usage
+	if res, err := g.R(ctx).Put(path.Join(users.BasePath, id, "recalculate_disk_usage")); err == nil {
+		_, err := blend4go.HandleResponse(res)
+		return err
+	} else {
+		return err
+	}
+}
+
+// Summary of a history as listed by the histories API
+type historyOwner struct {
+	Id     blend4go.GalaxyID `json:"id"`
+	UserId blend4go.GalaxyID `json:"user_id"`
+}
+
+// Histories listed per request while searching for the histories of a user
+const userHistoriesPageSize = 500
+
+// List the histories of a user that are not purged, including deleted histories that may still hold datasets.
+func userHistories(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID) ([]blend4go.GalaxyID, error) {
+	var owned []blend4go.GalaxyID
+	for _, deleted := range []string{"false", "true"} {
+		for offset := 0; ; offset += userHistoriesPageSize {
+			// GET /api/histories?all=true&q=user_id&qv={id}
+			res, err := g.R(ctx).SetResult(&[]*historyOwner{}).SetQueryParamsFromValues(url.Values{
+				"all":    {"true"},
+				"keys":   {"id,user_id"},
+				"q":      {"user_id", "deleted", "purged"},
+				"qv":     {id, deleted, "false"},
+				"limit":  {strconv.Itoa(userHistoriesPageSize)},
+				"offset": {strconv.Itoa(offset)},
+			}).Get(histories.BasePath)
+			if err != nil {
+				return nil, err
+			}
+			result, err := blend4go.HandleResponse(res)
+			if err != nil {
+				return nil, err
+			}
+			page := *result.(*[]*historyOwner)
+			for _, history := range page {
+				// Versions of Galaxy that ignore the owner filter list the histories of all users
+				if history.UserId == id {
+					owned = append(owned, history.Id)
+				}
+			}
+			if len(page) < userHistoriesPageSize {
+				break
+			}
+		}
+	}
+	return owned, nil
+}
+
+// Purge deleted datasets within all histories of user, including deleted histories that are not purged.
+// Returns true if any dataset was purged.
+func purgeDeletedDatasets(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID) (bool, error) {
+	owned, err := userHistories(ctx, g, id)
+	if err != nil {
+		return false, err
+	}
+	purged := false
+
+	for _, historyID := range owned {
+		items, err := listHistoryContents(ctx, g, historyID)
+		if err != nil {
+			return purged, err
+		}
+		for _, item := range items {
+			if item.HistoryContentType != "dataset" || !item.Deleted || item.Purged {
+				continue
+			}
+			// DELETE /api/histories/{history_id}/contents/{id}
+			if res, err := g.R(ctx).SetQueryParam("purge", "true").Delete(path.Join(histories.BasePath, historyID, "contents", item.Id)); err == nil {
+				if _, err := blend4go.HandleResponse(res); err != nil {
+					return purged, err
+				}
+				purged = true
+			} else {
+				return purged, err
+			}
+		}
+	}
+	return purged, nil
+}
+
+// Purge deleted datasets of user, recalculating disk usage if any dataset was purged and recalculation is enabled
+func purgeUserDatasets(ctx context.Context, g *blend4go.GalaxyInstance, d *schema.ResourceData) error {
+	purged, err := purgeDeletedDatasets(ctx, g, d.Id())
+	if err != nil || !purged || !d.Get("recalculate_disk_usage").(bool) {
+		return err
+	}
+	return recalculateDiskUsage(ctx, g, d.Id())
 }
 
 func resourceUserRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	g := m.(*blend4go.GalaxyInstance)
 
 	if user, err := users.Get(ctx, g, d.Id(), false); err == nil {
-		return handleUser(ctx, user, d)
+		diags := handleUser(ctx, user, d, "")
+		diags = append(diags, userStatusToSchema(ctx, g, d)...)
+		diags = append(diags, userPermissionsToSchema(ctx, g, d)...)
+		return append(diags, userMembershipToSchema(ctx, g, d)...)
 	} else {
 		return diag.FromErr(err)
 	}
@@ -163,29 +843,89 @@ func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, m interface
 	user := new(users.User)
 	user.SetGalaxyInstance(g)
 	diags = append(diags, fromSchema(user, d, nil)...)
-	if d.HasChange("password") {
-		current, change := d.GetChange("password")
-		if err := user.SetPassword(ctx, current.(string), change.(string)); err != nil {
+	manageExtra := d.Get("manage_extra_preferences").(bool)
+	if d.HasChanges("username", "email") || (manageExtra && d.HasChanges("extra_preferences", "manage_extra_preferences")) {
+		if err := putUserInformation(ctx, g, d); err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+	}
+	if d.HasChanges("default_access_roles", "default_manage_roles", "manage_permissions") && d.Get("manage_permissions").(bool) {
+		diags = append(diags, userPermissionsFromSchema(ctx, g, d)...)
+	}
+	// The previous password is not known, it may have been changed outside of terraform
+	if password := d.Get("password").(string); d.HasChange("password") && password != "" {
+		if err := resetUserPassword(ctx, g, d.Id(), password); err != nil {
 			diags = append(diags, diag.FromErr(err)...)
 		}
+		diags = append(diags, setPasswordHash(d)...)
 	}
 	if err := user.Update(ctx); err != nil {
 		diags = append(diags, diag.FromErr(err)...)
 	}
-	diags = append(diags, toSchema(user, d, userOmitFields)...)
+	if d.HasChange("active") {
+		if err := setUserActive(ctx, g, d.Id(), d.Get("active").(bool)); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	if d.HasChanges("groups", "roles", "manage_membership") && d.Get("manage_membership").(bool) {
+		diags = append(diags, userMembershipFromSchema(ctx, g, d)...)
+	}
+	if d.HasChange("purge_deleted_datasets") && d.Get("purge_deleted_datasets").(bool) {
+		if err := purgeUserDatasets(ctx, g, d); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+
+	// Verify that renames and extra preferences were applied
+	extra := d.Get("extra_preferences").(map[string]interface{})
+	if current, err := users.Get(ctx, g, d.Id(), false); err == nil {
+		for field, value := range map[string]string{"username": current.Username, "email": current.Email} {
+			if expected := d.Get(field).(string); value != expected {
+				diags = append(diags, diag.Errorf("user %v was not updated, expected %v %v but Galaxy reports %v", d.Id(), field, expected, value)...)
+			}
+		}
+		diags = append(diags, toSchema(current, d, userOmitFields)...)
+		diags = append(diags, userStatusToSchema(ctx, g, d)...)
+		if manageExtra {
+			diags = append(diags, verifyExtraPreferences(extra, d)...)
+		}
+		diags = append(diags, userPermissionsToSchema(ctx, g, d)...)
+		diags = append(diags, userMembershipToSchema(ctx, g, d)...)
+	} else {
+		diags = append(diags, diag.FromErr(err)...)
+	}
 
 	return diags
 }
 
+// Deactivate, delete, or purge user
+func destroyUser(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID, mode string) error {
+	if mode == "deactivate" {
+		return setUserActive(ctx, g, id, false)
+	}
+	user := &users.User{Id: id}
+	user.SetGalaxyInstance(g)
+	return user.Delete(ctx, mode == "purge")
+}
+
 func resourceUserDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
 	g := m.(*blend4go.GalaxyInstance)
-	user := new(users.User)
-	user.SetGalaxyInstance(g)
-	diags = append(diags, fromSchema(user, d, nil)...)
-	if err := user.Delete(ctx, d.Get("purge").(bool)); err != nil {
-		diags = append(diags, diag.FromErr(err)...)
-	}
 
-	return diags
+	mode := d.Get("on_destroy").(string)
+	if mode == "" {
+		mode = "purge"
+		if purge, ok := d.GetOkExists("purge"); ok && !purge.(bool) {
+			mode = "delete"
+		}
+	}
+	// Purging the user purges all of its datasets
+	if mode != "purge" && d.Get("purge_deleted_datasets").(bool) {
+		if _, err := purgeDeletedDatasets(ctx, g, d.Id()); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	if err := destroyUser(ctx, g, d.Id(), mode); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
 }
@@ -0,0 +1,158 @@
+package galaxy
+
+import (
+	"context"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/users"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"path"
+)
+
+func resourceUserAPIKey() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceUserAPIKeyCreate,
+		ReadContext:   resourceUserAPIKeyRead,
+		DeleteContext: resourceUserAPIKeyDelete,
+		Schema: map[string]*schema.Schema{
+			//"id": {
+			//	Type:     schema.TypeString,
+			//	Computed: true,
+			//},
+			"user_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of user to create API key for",
+			},
+			"rotation_triggers": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				ForceNew: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Arbitrary map of values that, when changed, will rotate the API key",
+			},
+			"key": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "API key of user",
+			},
+			"create_time": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Time API key was created",
+			},
+		},
+		Description: "API keys authenticate users against the Galaxy API. Keys are created through the admin API, so the users password is not required. A user has only one active key, creating a new key invalidates the previous key. Destroying the resource revokes the key if it is still active, which requires Galaxy 21.09 or later.",
+	}
+}
+
+// Response of GET /api/users/{id}/api_key/detailed
+type userAPIKey struct {
+	Key        string `json:"key"`
+	CreateTime string `json:"create_time"`
+}
+
+// Create a new API key for user, invalidating the previous key
+func createUserAPIKey(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID) (string, error) {
+	// POST /api/users/{id}/api_key
+	var key string
+	if res, err := g.R(ctx).SetResult(&key).Post(path.Join(users.BasePath, id, "api_key")); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return "", err
+		}
+	} else {
+		return "", err
+	}
+	return key, nil
+}
+
+func resourceUserAPIKeyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+	userID := d.Get("user_id").(string)
+
+	key, err := createUserAPIKey(ctx, g, userID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(userID)
+	if err := d.Set("key", key); err != nil {
+		return diag.FromErr(err)
+	}
+	return resourceUserAPIKeyRead(ctx, d, m)
+}
+
+func resourceUserAPIKeyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	// GET /api/users/{id}/api_key/detailed
+	if res, err := g.R(ctx).SetResult(&userAPIKey{}).Get(path.Join(users.BasePath, d.Id(), "api_key", "detailed")); err == nil {
+		switch res.StatusCode() {
+		case http.StatusNoContent:
+			// Key was deleted outside of terraform
+			d.SetId("")
+			return nil
+		case http.StatusNotFound:
+			// Galaxy versions prior to 21.09 can not report the current key
+			return nil
+		}
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			current := result.(*userAPIKey)
+			if current.Key != d.Get("key").(string) {
+				// Key was rotated outside of terraform
+				d.SetId("")
+				return nil
+			}
+			if err := d.Set("create_time", current.CreateTime); err != nil {
+				return diag.FromErr(err)
+			}
+		} else {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceUserAPIKeyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	// Only revoke the key if it is still the active key. A replacement key created before destroying this resource
+	// has already invalidated this key and must not be revoked.
+	// GET /api/users/{id}/api_key/detailed
+	if res, err := g.R(ctx).SetResult(&userAPIKey{}).Get(path.Join(users.BasePath, d.Id(), "api_key", "detailed")); err == nil {
+		switch res.StatusCode() {
+		case http.StatusNoContent:
+			// Key was already deleted
+			return nil
+		case http.StatusNotFound:
+			return diag.Errorf("Galaxy versions prior to 21.09 can not revoke API keys. Rotate the key or remove the resource from the state with terraform state rm.")
+		}
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			if result.(*userAPIKey).Key != d.Get("key").(string) {
+				// Key was already rotated
+				return nil
+			}
+		} else {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+
+	// DELETE /api/users/{id}/api_key
+	if res, err := g.R(ctx).Delete(path.Join(users.BasePath, d.Id(), "api_key")); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package galaxy_test
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"testing"
+)
+
+const UserAPIKeyResourcePath = "test-fixtures/user_api_key.tf"
+
+func testAccUserAPIKeyChanged(resourceName string, previous *string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return fmt.Errorf("not found: %s", resourceName)
+		}
+		key := rs.Primary.Attributes["key"]
+		if key == *previous {
+			return fmt.Errorf("API key was not rotated")
+		}
+		*previous = key
+		return nil
+	}
+}
+
+func TestAccUserAPIKey_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(UserAPIKeyResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_user_api_key." + name
+	var key string
+	type tmplFields struct {
+		Name     string
+		Rotation string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Rotation: "1"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair(resourceName, "user_id", "galaxy_user.test", "id"),
+					resource.TestCheckResourceAttrSet(resourceName, "key"),
+					testAccUserAPIKeyChanged(resourceName, &key),
+				),
+			},
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Rotation: "2"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccUserAPIKeyChanged(resourceName, &key),
+				),
+			},
+		},
+	})
+}
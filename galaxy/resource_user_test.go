@@ -2,10 +2,15 @@ package galaxy_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"github.com/brinkmanlab/blend4go"
 	"github.com/brinkmanlab/blend4go/users"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"path"
 	"testing"
 )
 
@@ -34,6 +39,20 @@ func testAccUserExists(resourceName string) resource.TestCheckFunc {
 	}
 }
 
+// Check that state holds a hash of the password rather than the password
+func testAccUserPasswordHash(resourceName, key, password string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return fmt.Errorf("Not found: %s", resourceName)
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(rs.Primary.Attributes[key]), []byte(password)); err != nil {
+			return fmt.Errorf("%s of %s is not stored as a hash: %v", key, resourceName, err)
+		}
+		return nil
+	}
+}
+
 func TestAccUser_basic(t *testing.T) {
 	tmpl := testAccConfigTemplate(UserResourcePath, t)
 	name := "test"
@@ -56,10 +75,204 @@ func TestAccUser_basic(t *testing.T) {
 					resource.TestCheckResourceAttr(resourceName, "username", "test"),
 					resource.TestCheckResourceAttr(resourceName, "email", "test@example.com"),
 					resource.TestCheckResourceAttrSet(resourceName, "api_key"),
+					resource.TestCheckResourceAttr(resourceName, "groups.#", "0"),
+					resource.TestCheckResourceAttr(resourceName, "extra_preferences.%", "0"),
+					resource.TestCheckResourceAttr(resourceName, "default_access_roles.#", "0"),
 					//testCheckResourceAttrEqual(resourceName, "deleted", false),
 					//testCheckResourceAttrEqual(resourceName, "purged", false),
 				),
 			},
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Username: "test-renamed", Password: "testpass2", Email: "renamed@example.com"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccUserExists(resourceName),
+					testAccUserPasswordHash(resourceName, "password", "testpass2"),
+					resource.TestCheckResourceAttr(resourceName, "username", "test-renamed"),
+					resource.TestCheckResourceAttr(resourceName, "email", "renamed@example.com"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccUser_deactivate(t *testing.T) {
+	tmpl := testAccConfigTemplate("test-fixtures/user_deactivate.tf", t)
+	name := "test"
+	resourceName := "galaxy_user." + name
+	type tmplFields struct {
+		Name     string
+		Username string
+		Email    string
+		Active   bool
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		IDRefreshName:     resourceName,
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Username: "test-deactivate", Email: "deactivate@example.com", Active: false}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccUserExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "active", "false"),
+					resource.TestCheckNoResourceAttr(resourceName, "api_key"),
+				),
+			},
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Username: "test-deactivate", Email: "deactivate@example.com", Active: true}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccUserExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "active", "true"),
+				),
+			},
+		},
+	})
+}
+
+// Create a group or role, returning its id. The item is deleted once the test completes. blend4go does not implement groups or roles.
+func testAccCreateItem(t *testing.T, endpoint string, body map[string]interface{}) string {
+	res, err := testAccGalaxyInstance().R(context.Background()).SetBody(body).Post(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blend4go.HandleResponse(res); err != nil {
+		t.Fatal(err)
+	}
+	// Some Galaxy versions respond with a list of the created items
+	item := struct {
+		Id string `json:"id"`
+	}{}
+	items := []*struct {
+		Id string `json:"id"`
+	}{&item}
+	if err := json.Unmarshal(res.Body(), &item); err != nil {
+		if err := json.Unmarshal(res.Body(), &items); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		// DELETE /api/{groups,roles}/{id}
+		res, err := testAccGalaxyInstance().R(context.Background()).Delete(path.Join(endpoint, item.Id))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			t.Error(err)
+		}
+	})
+	return item.Id
+}
+
+// Skip tests that create Galaxy items before rendering their configuration
+func testAccSkipUnlessAcc(t *testing.T) {
+	if os.Getenv(resource.TestEnvVar) == "" {
+		t.Skipf("Acceptance tests skipped unless env '%s' set", resource.TestEnvVar)
+	}
+	testAccPreCheck(t)()
+}
+
+func TestAccUser_membership(t *testing.T) {
+	tmpl := testAccConfigTemplate("test-fixtures/user_membership.tf", t)
+	name := "test"
+	resourceName := "galaxy_user." + name
+	// The group must exist before the configuration is rendered
+	testAccSkipUnlessAcc(t)
+	// POST /api/groups
+	group := testAccCreateItem(t, "/api/groups", map[string]interface{}{"name": "test-membership"})
+	type tmplFields struct {
+		Name     string
+		Username string
+		Email    string
+		Groups   []string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		IDRefreshName:     resourceName,
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Username: "test-membership", Email: "membership@example.com", Groups: []string{group}}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccUserExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "groups.#", "1"),
+				),
+			},
+			{
+				// Removing the last group removes the membership
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Username: "test-membership", Email: "membership@example.com"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccUserExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "groups.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccUser_defaults(t *testing.T) {
+	tmpl := testAccConfigTemplate("test-fixtures/user_defaults.tf", t)
+	name := "test"
+	resourceName := "galaxy_user." + name
+	// The role must exist before the configuration is rendered
+	testAccSkipUnlessAcc(t)
+	// POST /api/roles
+	role := testAccCreateItem(t, "/api/roles", map[string]interface{}{"name": "test-defaults", "description": "test", "user_ids": []string{}, "group_ids": []string{}})
+	// Extra preferences must be configured in Galaxy's user_preferences_extra_conf.yml
+	extra := map[string]string{}
+	if field := os.Getenv("GALAXY_EXTRA_PREFERENCE"); field != "" {
+		extra[field] = "test"
+	}
+	type tmplFields struct {
+		Name             string
+		Roles            []string
+		ExtraPreferences map[string]string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		IDRefreshName:     resourceName,
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Roles: []string{role}, ExtraPreferences: extra}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccUserExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "default_access_roles.#", "1"),
+					resource.TestCheckResourceAttr(resourceName, "extra_preferences.%", fmt.Sprint(len(extra))),
+				),
+			},
+			{
+				// Clearing the values removes them from Galaxy
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccUserExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "default_access_roles.#", "0"),
+					resource.TestCheckResourceAttr(resourceName, "extra_preferences.%", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccUser_diskUsage(t *testing.T) {
+	tmpl := testAccConfigTemplate("test-fixtures/user_disk_usage.tf", t)
+	name := "test"
+	resourceName := "galaxy_user." + name
+	type tmplFields struct {
+		Name string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		IDRefreshName:     resourceName,
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccUserExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "total_disk_usage", "0"),
+				),
+			},
 		},
 	})
 }
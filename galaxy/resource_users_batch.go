@@ -0,0 +1,556 @@
+package galaxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/users"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func resourceUsersBatch() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceUsersBatchCreate,
+		ReadContext:   resourceUsersBatchRead,
+		UpdateContext: resourceUsersBatchUpdate,
+		DeleteContext: resourceUsersBatchDelete,
+		CustomizeDiff: resourceUsersBatchCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			//"id": {
+			//	Type:     schema.TypeString,
+			//	Computed: true,
+			//},
+			"user": {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"email": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Users email address, identifies the user within the batch",
+						},
+						"username": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Username to identify user",
+						},
+						"password": {
+							Type:      schema.TypeString,
+							Optional:  true,
+							Sensitive: true,
+							DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+								// State holds a hash of the password, compare against the user with the same email as users may be reordered
+								o, _ := d.GetChange("user")
+								user, ok := batchUsers(o)[d.Get(strings.TrimSuffix(k, "password")+"email").(string)]
+								return ok && passwordMatches(user.Password, new)
+							},
+							Description: "Password to authenticate user against Galaxy. A password is generated if unset. Only a salted bcrypt hash of the password is stored in state.",
+						},
+						"expiry": {
+							Type:     schema.TypeString,
+							Optional: true,
+							ValidateDiagFunc: func(v interface{}, path cty.Path) diag.Diagnostics {
+								if _, err := time.Parse(time.RFC3339, v.(string)); err != nil {
+									diags := diag.Errorf("invalid expiry %s, expected RFC3339 timestamp", v)
+									diags[0].AttributePath = path
+									return diags
+								}
+								return nil
+							},
+							Description: "Time after which the user is removed by the next apply, in RFC3339 format",
+						},
+					},
+				},
+				Description: "Repeatable block of users to provision",
+			},
+			"concurrency": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     8,
+				Description: "Maximum number of concurrent API requests",
+			},
+			"on_destroy": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "purge",
+				ValidateDiagFunc: validateUserDestroyMode,
+				Description:      "Action taken on users removed from the batch, expired, or when the resource is destroyed (deactivate, delete, purge)",
+			},
+			"ids": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Map of user ids keyed on email address",
+			},
+			"passwords": {
+				Type:      schema.TypeMap,
+				Computed:  true,
+				Sensitive: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Map of generated passwords keyed on email address, for users without a configured password",
+			},
+			"api_keys": {
+				Type:      schema.TypeMap,
+				Computed:  true,
+				Sensitive: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Map of API keys keyed on email address",
+			},
+		},
+		Description: "Provision many users at once, such as temporary accounts for a workshop. Users are reconciled with concurrent API requests and identified by email address. Users that no longer exist in Galaxy are recreated. Users that fail to provision are reported as warnings and retried by the next apply.",
+	}
+}
+
+type batchUser struct {
+	Email    string
+	Username string
+	Password string
+	Expiry   string
+}
+
+// Users have expired if the expiry time has passed
+func (u *batchUser) expired(now time.Time) bool {
+	if u.Expiry == "" {
+		return false
+	}
+	expiry, err := time.Parse(time.RFC3339, u.Expiry)
+	return err == nil && now.After(expiry)
+}
+
+// Convert user blocks to users keyed on email
+func batchUsers(v interface{}) map[string]*batchUser {
+	result := map[string]*batchUser{}
+	for _, u := range v.([]interface{}) {
+		user := u.(map[string]interface{})
+		result[user["email"].(string)] = &batchUser{
+			Email:    user["email"].(string),
+			Username: user["username"].(string),
+			Password: user["password"].(string),
+			Expiry:   user["expiry"].(string),
+		}
+	}
+	return result
+}
+
+// Convert map attribute to map of strings
+func stringMap(v interface{}) map[string]string {
+	result := map[string]string{}
+	for k, v := range v.(map[string]interface{}) {
+		result[k] = v.(string)
+	}
+	return result
+}
+
+// Run f for each key with at most n concurrent calls, returning errors keyed on key
+func runConcurrently(keys []string, n int, f func(key string) error) map[string]error {
+	if n < 1 {
+		n = 1
+	}
+	errs := map[string]error{}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, n)
+	for _, key := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(key string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := f(key); err != nil {
+				mutex.Lock()
+				errs[key] = err
+				mutex.Unlock()
+			}
+		}(key)
+	}
+	wg.Wait()
+	return errs
+}
+
+// Convert errors keyed on email to diagnostics ordered by email
+func batchDiagnostics(errs map[string]error) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var emails []string
+	for email := range errs {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	for _, email := range emails {
+		diags = append(diags, diag.Errorf("user %v: %v", email, errs[email])...)
+	}
+	return diags
+}
+
+// Convert errors of users that failed to provision to warnings, failing the whole batch would taint it.
+// Users that failed are left out of ids and are dropped from the user list on refresh, so the next apply retries them.
+func batchProvisionDiagnostics(errs map[string]error) diag.Diagnostics {
+	diags := batchDiagnostics(errs)
+	for i := range diags {
+		diags[i].Severity = diag.Warning
+		diags[i].Summary = diags[i].Summary + ", the user will be retried by the next apply"
+	}
+	return diags
+}
+
+// Results of provisioning a batch, safe for concurrent use
+type batchState struct {
+	sync.Mutex
+	ids       map[string]string
+	passwords map[string]string
+	apiKeys   map[string]string
+}
+
+func newBatchState(d *schema.ResourceData) *batchState {
+	return &batchState{
+		ids:       stringMap(d.Get("ids")),
+		passwords: stringMap(d.Get("passwords")),
+		apiKeys:   stringMap(d.Get("api_keys")),
+	}
+}
+
+func (s *batchState) set(email, id, password, apiKey string) {
+	s.Lock()
+	defer s.Unlock()
+	s.ids[email] = id
+	if password != "" {
+		s.passwords[email] = password
+	} else {
+		delete(s.passwords, email)
+	}
+	if apiKey != "" {
+		s.apiKeys[email] = apiKey
+	}
+}
+
+func (s *batchState) remove(email string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.ids, email)
+	delete(s.passwords, email)
+	delete(s.apiKeys, email)
+}
+
+func (s *batchState) id(email string) (string, bool) {
+	s.Lock()
+	defer s.Unlock()
+	id, ok := s.ids[email]
+	return id, ok
+}
+
+func (s *batchState) toSchema(d *schema.ResourceData) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for k, v := range map[string]interface{}{
+		"ids":       s.ids,
+		"passwords": s.passwords,
+		"api_keys":  s.apiKeys,
+	} {
+		if err := d.Set(k, v); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	return diags
+}
+
+// Check if the configured password of a user is unchanged from the hash in state for the same email.
+// Diffs are suppressed per list position, an unchanged password may hold the hash of the user previously at its position.
+func batchPasswordUnchanged(previous map[string]*batchUser, email, password string) bool {
+	old, ok := previous[email]
+	if !ok {
+		return false
+	}
+	if password == old.Password || passwordMatches(old.Password, password) {
+		return true
+	}
+	for _, user := range previous {
+		if user.Password != "" && user.Password == password {
+			return true
+		}
+	}
+	return false
+}
+
+// Replace configured passwords with their hash before the state is stored.
+// Unchanged passwords retain the hash already in state for the same email.
+func setBatchPasswordHashes(d *schema.ResourceData) diag.Diagnostics {
+	o, n := d.GetChange("user")
+	previous := batchUsers(o)
+	list := n.([]interface{})
+	for _, u := range list {
+		user := u.(map[string]interface{})
+		email := user["email"].(string)
+		if batchPasswordUnchanged(previous, email, user["password"].(string)) {
+			user["password"] = previous[email].Password
+			continue
+		}
+		if password := user["password"].(string); password != "" {
+			hash, err := hashPassword(password)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			user["password"] = hash
+		}
+	}
+	if err := d.Set("user", list); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+// Create or undelete user, recording its id, generated password and API key
+func provisionBatchUser(ctx context.Context, g *blend4go.GalaxyInstance, state *batchState, user *batchUser) error {
+	password := user.Password
+	var generated string
+	if password == "" {
+		var err error
+		if password, err = randomPassword(); err != nil {
+			return err
+		}
+		generated = password
+	}
+	created, undeleted, err := createUser(ctx, g, user.Username, password, user.Email)
+	if err != nil {
+		return err
+	}
+	if undeleted {
+		if err := resetUserPassword(ctx, g, created.Id, password); err != nil {
+			return err
+		}
+	}
+	state.set(user.Email, created.Id, generated, "")
+	apiKey, err := createUserAPIKey(ctx, g, created.Id)
+	if err != nil {
+		return err
+	}
+	state.set(user.Email, created.Id, generated, apiKey)
+	return nil
+}
+
+func resourceUsersBatchCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	// Users are identified by email within the batch
+	seen := map[string]bool{}
+	for _, u := range d.Get("user").([]interface{}) {
+		email := u.(map[string]interface{})["email"].(string)
+		if email == "" {
+			// Unknown until apply
+			continue
+		}
+		if seen[email] {
+			return fmt.Errorf("user %v is listed more than once", email)
+		}
+		seen[email] = true
+	}
+
+	// Users that expired since the last apply must be removed
+	expired := false
+	ids := d.Get("ids").(map[string]interface{})
+	now := time.Now()
+	for email, user := range batchUsers(d.Get("user")) {
+		if _, ok := ids[email]; ok && user.expired(now) {
+			expired = true
+		}
+	}
+	if d.HasChange("user") || expired {
+		for _, k := range []string{"ids", "passwords", "api_keys"} {
+			if err := d.SetNewComputed(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resourceUsersBatchCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+	state := &batchState{ids: map[string]string{}, passwords: map[string]string{}, apiKeys: map[string]string{}}
+
+	configured := batchUsers(d.Get("user"))
+	now := time.Now()
+	var emails []string
+	for email, user := range configured {
+		if !user.expired(now) {
+			emails = append(emails, email)
+		}
+	}
+	sort.Strings(emails)
+	d.SetId(HashString(strings.Join(emails, ",")))
+
+	errs := runConcurrently(emails, d.Get("concurrency").(int), func(email string) error {
+		return provisionBatchUser(ctx, g, state, configured[email])
+	})
+	diags := batchProvisionDiagnostics(errs)
+	diags = append(diags, setBatchPasswordHashes(d)...)
+	return append(diags, state.toSchema(d)...)
+}
+
+func resourceUsersBatchRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+	state := newBatchState(d)
+
+	var emails []string
+	for email := range state.ids {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	var mutex sync.Mutex
+	usernames := map[string]string{}
+	errs := runConcurrently(emails, d.Get("concurrency").(int), func(email string) error {
+		id, _ := state.id(email)
+		// GET /api/users/{id}
+		if res, err := g.R(ctx).SetResult(&users.User{}).Get(path.Join(users.BasePath, id)); err == nil {
+			if res.StatusCode() == http.StatusNotFound {
+				state.remove(email)
+				return nil
+			}
+			if result, err := blend4go.HandleResponse(res); err == nil {
+				if user := result.(*users.User); user.Deleted {
+					state.remove(email)
+				} else {
+					mutex.Lock()
+					usernames[email] = user.Username
+					mutex.Unlock()
+				}
+			} else {
+				return err
+			}
+		} else {
+			return err
+		}
+		return nil
+	})
+	diags := batchDiagnostics(errs)
+
+	// Drop users that no longer exist so that they are recreated, report renames made outside of terraform
+	var list []map[string]interface{}
+	now := time.Now()
+	for _, u := range d.Get("user").([]interface{}) {
+		user := u.(map[string]interface{})
+		email := user["email"].(string)
+		if _, ok := state.ids[email]; !ok && !(&batchUser{Expiry: user["expiry"].(string)}).expired(now) {
+			if _, failed := errs[email]; !failed {
+				continue
+			}
+		}
+		if username, ok := usernames[email]; ok {
+			user["username"] = username
+		}
+		list = append(list, user)
+	}
+	if err := d.Set("user", list); err != nil {
+		diags = append(diags, diag.FromErr(err)...)
+	}
+	return append(diags, state.toSchema(d)...)
+}
+
+func resourceUsersBatchUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+	state := newBatchState(d)
+	mode := d.Get("on_destroy").(string)
+
+	o, n := d.GetChange("user")
+	previous := batchUsers(o)
+	configured := batchUsers(n)
+	now := time.Now()
+
+	emails := map[string]bool{}
+	for email := range state.ids {
+		emails[email] = true
+	}
+	for email := range configured {
+		emails[email] = true
+	}
+	var keys []string
+	for email := range emails {
+		keys = append(keys, email)
+	}
+	sort.Strings(keys)
+
+	var mutex sync.Mutex
+	provisionErrs := map[string]error{}
+	errs := runConcurrently(keys, d.Get("concurrency").(int), func(email string) error {
+		id, exists := state.id(email)
+		user, ok := configured[email]
+		switch {
+		case exists && (!ok || user.expired(now)):
+			if err := destroyUser(ctx, g, id, mode); err != nil {
+				return err
+			}
+			state.remove(email)
+		case !exists && ok && !user.expired(now):
+			if err := provisionBatchUser(ctx, g, state, user); err != nil {
+				mutex.Lock()
+				provisionErrs[email] = err
+				mutex.Unlock()
+			}
+		case exists:
+			old, hasOld := previous[email]
+			if !hasOld || old.Username != user.Username {
+				if err := renameUser(ctx, g, id, user.Username, email); err != nil {
+					return err
+				}
+			}
+			// State holds a hash of the previous password
+			if hasOld && !batchPasswordUnchanged(previous, email, user.Password) {
+				password := user.Password
+				var generated string
+				if password == "" {
+					var err error
+					if password, err = randomPassword(); err != nil {
+						return err
+					}
+					generated = password
+				}
+				if err := resetUserPassword(ctx, g, id, password); err != nil {
+					return err
+				}
+				state.set(email, id, generated, "")
+			}
+		}
+		return nil
+	})
+	diags := append(batchDiagnostics(errs), batchProvisionDiagnostics(provisionErrs)...)
+	diags = append(diags, setBatchPasswordHashes(d)...)
+	return append(diags, state.toSchema(d)...)
+}
+
+func resourceUsersBatchDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+	state := newBatchState(d)
+	mode := d.Get("on_destroy").(string)
+
+	var emails []string
+	for email := range state.ids {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	errs := runConcurrently(emails, d.Get("concurrency").(int), func(email string) error {
+		id, _ := state.id(email)
+		if err := destroyUser(ctx, g, id, mode); err != nil {
+			return fmt.Errorf("failed to %v: %v", mode, err)
+		}
+		state.remove(email)
+		return nil
+	})
+	if len(errs) > 0 {
+		// Retain the users that could not be removed
+		return append(batchDiagnostics(errs), state.toSchema(d)...)
+	}
+	return nil
+}
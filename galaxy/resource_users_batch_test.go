@@ -0,0 +1,67 @@
+package galaxy_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"regexp"
+	"testing"
+)
+
+const UsersBatchResourcePath = "test-fixtures/users_batch.tf"
+
+func TestAccUsersBatch_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(UsersBatchResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_users_batch." + name
+	type batchUser struct {
+		Email    string
+		Username string
+		Password string
+		Expiry   string
+	}
+	type tmplFields struct {
+		Name  string
+		Users []batchUser
+	}
+	first := batchUser{Email: "workshop1@example.com", Username: "workshop1", Password: "workshop"}
+	second := batchUser{Email: "workshop2@example.com", Username: "workshop2"}
+	third := batchUser{Email: "workshop3@example.com", Username: "workshop3"}
+	expired := batchUser{Email: "workshop4@example.com", Username: "workshop4", Expiry: "2000-01-01T00:00:00Z"}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Users: []batchUser{first, second, expired}}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "ids.%", "2"),
+					resource.TestCheckResourceAttrSet(resourceName, "ids.workshop1@example.com"),
+					resource.TestCheckNoResourceAttr(resourceName, "ids.workshop4@example.com"),
+					resource.TestCheckResourceAttrSet(resourceName, "passwords.workshop2@example.com"),
+					resource.TestCheckResourceAttrSet(resourceName, "api_keys.workshop2@example.com"),
+					testAccUserPasswordHash(resourceName, "user.0.password", "workshop"),
+				),
+			},
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Users: []batchUser{first, third}}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "ids.%", "2"),
+					resource.TestCheckNoResourceAttr(resourceName, "ids.workshop2@example.com"),
+					resource.TestCheckResourceAttrSet(resourceName, "ids.workshop3@example.com"),
+				),
+			},
+			{
+				// Reordering users keeps the password hash of each user
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Users: []batchUser{third, first}}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "ids.%", "2"),
+					resource.TestCheckResourceAttr(resourceName, "user.0.password", ""),
+					testAccUserPasswordHash(resourceName, "user.1.password", "workshop"),
+				),
+			},
+			{
+				Config:      testAccConfig(tmpl, t, &tmplFields{Name: name, Users: []batchUser{first, third, {Email: third.Email, Username: "workshop5"}}}),
+				ExpectError: regexp.MustCompile("user workshop3@example.com is listed more than once"),
+			},
+		},
+	})
+}
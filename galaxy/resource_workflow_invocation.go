@@ -0,0 +1,398 @@
+package galaxy
+
+import (
+	"context"
+	"errors"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/brinkmanlab/blend4go/workflows"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"path"
+	"time"
+)
+
+const invocationsBasePath = "/api/invocations"
+
+var invocationScheduled = map[string]bool{
+	"new":        false,
+	"ready":      false,
+	"cancelling": false,
+	"scheduled":  true,
+	"cancelled":  true,
+	"failed":     true,
+}
+
+func resourceWorkflowInvocation() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceWorkflowInvocationCreate,
+		ReadContext:   resourceWorkflowInvocationRead,
+		DeleteContext: resourceWorkflowInvocationDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(time.Hour),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+		Schema: map[string]*schema.Schema{
+			//"id": {
+			//	Type:     schema.TypeString,
+			//	Computed: true,
+			//},
+			"workflow_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Id of stored workflow to invoke",
+			},
+			"history_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"history_id", "history_name"},
+				Description:  "Id of existing history where workflow outputs are associated",
+			},
+			"history_name": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"history_id", "history_name"},
+				Description:  "Name of new history to create for workflow outputs",
+			},
+			"input": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"label": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Label of workflow input",
+						},
+						"id": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "HDA or HDCA id for dataset and collection inputs",
+						},
+						"src": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "hda",
+							Description: "Source of dataset input (hda, hdca, ldda)",
+						},
+						"value": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Value for parameter inputs",
+						},
+					},
+				},
+				Description: "Repeatable block of workflow inputs keyed on input label",
+			},
+			"step_parameter": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"step": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Step label or index",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Tool parameter name",
+						},
+						"value": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Tool parameter value",
+						},
+					},
+				},
+				Description: "Repeatable block of tool parameter overrides for workflow steps",
+			},
+			"wait_for_completion": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				ForceNew:    true,
+				Description: "Wait for all jobs of the invocation to complete before creating dependant resources",
+			},
+			"state": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Scheduling state of invocation",
+			},
+			"update_time": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Time invocation last updated",
+			},
+			"steps": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"order_index": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Index of step within workflow",
+						},
+						"label": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Step label",
+						},
+						"state": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Step state",
+						},
+						"job_id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Id of job executed for step",
+						},
+					},
+				},
+				Description: "List of invocation steps",
+			},
+			"outputs": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Map of output HDA ids keyed on workflow output label",
+			},
+			"output_collections": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Description: "Map of output HDCA ids keyed on workflow output label",
+			},
+		},
+		Importer:    &schema.ResourceImporter{StateContext: schema.ImportStatePassthroughContext},
+		Description: "Run a stored workflow. Outputs are associated with the given history, or a new history if a name is provided instead. Waiting for the invocation to be scheduled, and for its jobs to complete if wait_for_completion is set, times out after an hour by default. Cancelling the invocation on destroy times out after 10 minutes. Both are configurable with a timeouts block.",
+	}
+}
+
+type invocationOutput struct {
+	Id  blend4go.GalaxyID `json:"id"`
+	Src string            `json:"src"`
+}
+
+// Response of GET /api/invocations/{id}
+type workflowInvocation struct {
+	Id                blend4go.GalaxyID                   `json:"id"`
+	State             string                              `json:"state"`
+	UpdateTime        string                              `json:"update_time"`
+	HistoryId         blend4go.GalaxyID                   `json:"history_id"`
+	Steps             []*workflows.WorkflowInvocationStep `json:"steps"`
+	Outputs           map[string]*invocationOutput        `json:"outputs"`
+	OutputCollections map[string]*invocationOutput        `json:"output_collections"`
+}
+
+// Response of GET /api/invocations/{id}/jobs_summary
+type invocationJobsSummary struct {
+	States map[string]uint `json:"states"`
+}
+
+func getInvocation(ctx context.Context, g *blend4go.GalaxyInstance, id blend4go.GalaxyID) (*workflowInvocation, error) {
+	// GET /api/invocations/{id}
+	if res, err := g.R(ctx).SetResult(&workflowInvocation{}).Get(path.Join(invocationsBasePath, id)); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			return result.(*workflowInvocation), nil
+		} else {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+}
+
+func invocationToSchema(invocation *workflowInvocation, d *schema.ResourceData) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var steps []map[string]interface{}
+	for _, step := range invocation.Steps {
+		steps = append(steps, map[string]interface{}{
+			"order_index": step.OrderIndex,
+			"label":       step.WorkflowStepLabel,
+			"state":       step.State,
+			"job_id":      step.JobId,
+		})
+	}
+	outputs := map[string]string{}
+	for label, output := range invocation.Outputs {
+		outputs[label] = output.Id
+	}
+	outputCollections := map[string]string{}
+	for label, output := range invocation.OutputCollections {
+		outputCollections[label] = output.Id
+	}
+	for k, v := range map[string]interface{}{
+		"history_id":         invocation.HistoryId,
+		"state":              invocation.State,
+		"update_time":        invocation.UpdateTime,
+		"steps":              steps,
+		"outputs":            outputs,
+		"output_collections": outputCollections,
+	} {
+		if err := d.Set(k, v); err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+	d.SetId(invocation.Id)
+	return diags
+}
+
+// Wait for invocation to be scheduled and optionally for all of its jobs to complete.
+// Returns the most recently fetched invocation, even on error.
+func waitForInvocation(ctx context.Context, g *blend4go.GalaxyInstance, invocation *workflowInvocation, jobsComplete bool) (*workflowInvocation, error) {
+	for !invocationScheduled[invocation.State] {
+		time.Sleep(2 * time.Second)
+		if ctx.Err() != nil {
+			return invocation, ctx.Err()
+		}
+		if current, err := getInvocation(ctx, g, invocation.Id); err == nil {
+			invocation = current
+		} else {
+			return invocation, err
+		}
+	}
+	if invocation.State != "scheduled" {
+		return invocation, errors.New("galaxy_workflow_invocation " + invocation.State + ", see " + g.Client.HostURL + path.Join(invocationsBasePath, invocation.Id) + " for more info")
+	}
+
+	for complete := !jobsComplete; !complete; {
+		time.Sleep(2 * time.Second)
+		if ctx.Err() != nil {
+			return invocation, ctx.Err()
+		}
+		// GET /api/invocations/{id}/jobs_summary
+		if res, err := g.R(ctx).SetResult(&invocationJobsSummary{}).Get(path.Join(invocationsBasePath, invocation.Id, "jobs_summary")); err == nil {
+			if result, err := blend4go.HandleResponse(res); err == nil {
+				complete = true
+				for state, count := range result.(*invocationJobsSummary).States {
+					complete = complete && (count == 0 || jobEnded[state])
+					if state == "error" && count > 0 {
+						return invocation, errors.New("galaxy_workflow_invocation jobs failed execution, see " + g.Client.HostURL + path.Join(invocationsBasePath, invocation.Id) + " for more info")
+					}
+				}
+			} else {
+				return invocation, err
+			}
+		} else {
+			return invocation, err
+		}
+	}
+
+	// Refresh to collect outputs
+	if current, err := getInvocation(ctx, g, invocation.Id); err == nil {
+		return current, nil
+	} else {
+		return invocation, err
+	}
+}
+
+func resourceWorkflowInvocationCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	inputs := map[string]interface{}{}
+	for _, i := range d.Get("input").([]interface{}) {
+		input := i.(map[string]interface{})
+		if id := input["id"].(string); id != "" {
+			inputs[input["label"].(string)] = map[string]string{"id": id, "src": input["src"].(string)}
+		} else {
+			inputs[input["label"].(string)] = input["value"].(string)
+		}
+	}
+	parameters := map[string]map[string]string{}
+	for _, p := range d.Get("step_parameter").([]interface{}) {
+		param := p.(map[string]interface{})
+		step := param["step"].(string)
+		if _, ok := parameters[step]; !ok {
+			parameters[step] = map[string]string{}
+		}
+		parameters[step][param["name"].(string)] = param["value"].(string)
+	}
+
+	payload := map[string]interface{}{
+		"inputs":     inputs,
+		"inputs_by":  "name",
+		"parameters": parameters,
+	}
+	if id, ok := d.GetOk("history_id"); ok {
+		payload["history_id"] = id.(string)
+	}
+	if name, ok := d.GetOk("history_name"); ok {
+		payload["history"] = name.(string)
+	}
+
+	// POST /api/workflows/{workflow_id}/invocations
+	if res, err := g.R(ctx).SetResult(&workflowInvocation{}).SetBody(payload).Post(path.Join(workflows.BasePath, d.Get("workflow_id").(string), "invocations")); err == nil {
+		if result, err := blend4go.HandleResponse(res); err == nil {
+			var diags diag.Diagnostics
+			created := result.(*workflowInvocation)
+			// Record the invocation so that it is tracked even if waiting fails
+			d.SetId(created.Id)
+			invocation, err := waitForInvocation(ctx, g, created, d.Get("wait_for_completion").(bool))
+			if err != nil {
+				diags = append(diags, diag.FromErr(err)...)
+			}
+			if invocation != nil {
+				diags = append(diags, invocationToSchema(invocation, d)...)
+			}
+			return diags
+		} else {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+}
+
+func resourceWorkflowInvocationRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	if invocation, err := getInvocation(ctx, g, d.Id()); err == nil {
+		return invocationToSchema(invocation, d)
+	} else {
+		return diag.FromErr(err)
+	}
+}
+
+func resourceWorkflowInvocationDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	g := m.(*blend4go.GalaxyInstance)
+
+	// Cancel the invocation if it is still being scheduled, outputs remain in the history
+	if invocationScheduled[d.Get("state").(string)] {
+		return nil
+	}
+	// DELETE /api/invocations/{id}
+	if res, err := g.R(ctx).Delete(path.Join(invocationsBasePath, d.Id())); err == nil {
+		if _, err := blend4go.HandleResponse(res); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		return diag.FromErr(err)
+	}
+	// Wait for Galaxy to finish cancelling the invocation
+	for {
+		invocation, err := getInvocation(ctx, g, d.Id())
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if invocationScheduled[invocation.State] {
+			return nil
+		}
+		time.Sleep(2 * time.Second)
+		if ctx.Err() != nil {
+			return diag.FromErr(ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package galaxy_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/brinkmanlab/blend4go"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"path"
+	"testing"
+)
+
+const WorkflowInvocationResourcePath = "test-fixtures/workflow_invocation.tf"
+
+func testAccWorkflowInvocationExists(resourceName string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return fmt.Errorf("Not found: %s", resourceName)
+		}
+
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("ID unset")
+		}
+
+		if res, err := testAccGalaxyInstance().R(context.Background()).SetResult(&map[string]interface{}{}).Get(path.Join("/api/invocations", rs.Primary.ID)); err == nil {
+			if result, err := blend4go.HandleResponse(res); err == nil {
+				if id := (*result.(*map[string]interface{}))["id"]; id != rs.Primary.ID {
+					return fmt.Errorf("ID mismatch between stored ID (%v) and fetched (%v)", rs.Primary.ID, id)
+				}
+			} else {
+				return err
+			}
+		} else {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func TestAccWorkflowInvocation_basic(t *testing.T) {
+	tmpl := testAccConfigTemplate(WorkflowInvocationResourcePath, t)
+	name := "test"
+	resourceName := "galaxy_workflow_invocation." + name
+	workflow, _, err := loadWorkflow(WorkflowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type tmplFields struct {
+		Name string
+		Json string
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          testAccPreCheck(t),
+		IDRefreshName:     resourceName,
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConfig(tmpl, t, &tmplFields{Name: name, Json: workflow}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testAccWorkflowInvocationExists(resourceName),
+					resource.TestCheckResourceAttr(resourceName, "state", "scheduled"),
+					resource.TestCheckResourceAttrSet(resourceName, "steps.0.job_id"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,11 @@
+resource "galaxy_user" "test" {
+  username = "test-custom-build"
+  email = "custom-build@example.com"
+}
+
+resource "galaxy_custom_build" "{{ .Name }}" {
+  user_id = galaxy_user.test.id
+  key = "test_build"
+  name = "{{ .BuildName }}"
+  len = "chr1\t1000\nchr2\t2000\n"
+}
@@ -0,0 +1,15 @@
+resource "galaxy_history" "test" {
+  name = "test"
+}
+
+resource "galaxy_dataset" "test" {
+  history_id = galaxy_history.test.id
+  name = "{{ .DatasetName }}"
+  content = "foo"
+  file_type = "txt"
+}
+
+data "galaxy_dataset" "{{ .Name }}" {
+  history_id = galaxy_history.test.id
+  name = galaxy_dataset.test.name
+}
\ No newline at end of file
@@ -0,0 +1,10 @@
+resource "galaxy_stored_workflow" "test" {
+  json = <<EOF
+{{ .Json -}}
+EOF
+}
+
+data "galaxy_stored_workflow" "{{ .Name }}" {
+  id     = galaxy_stored_workflow.test.id
+  format = "{{ .Format }}"
+}
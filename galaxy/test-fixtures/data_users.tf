@@ -0,0 +1,8 @@
+resource "galaxy_user" "test" {
+  username = "test-users"
+  email = "test-users@example.com"
+}
+
+data "galaxy_users" "{{ .Name }}" {
+  email = galaxy_user.test.email
+}
@@ -0,0 +1,10 @@
+resource "galaxy_history" "test" {
+  name = "test"
+}
+
+resource "galaxy_dataset" "{{ .Name }}" {
+  history_id = galaxy_history.test.id
+  name = "{{ .DatasetName }}"
+  content = "{{ .Content }}"
+  file_type = "txt"
+}
\ No newline at end of file
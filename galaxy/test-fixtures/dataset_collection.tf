@@ -0,0 +1,29 @@
+resource "galaxy_history" "test" {
+  name = "test"
+}
+
+resource "galaxy_dataset" "forward" {
+  history_id = galaxy_history.test.id
+  content = "@r1\nACGT\n+\nIIII\n"
+  file_type = "fastqsanger"
+}
+
+resource "galaxy_dataset" "reverse" {
+  history_id = galaxy_history.test.id
+  content = "@r1\nTGCA\n+\nIIII\n"
+  file_type = "fastqsanger"
+}
+
+resource "galaxy_dataset_collection" "{{ .Name }}" {
+  history_id = galaxy_history.test.id
+  name = "{{ .CollectionName }}"
+  collection_type = "paired"
+  element {
+    identifier = "forward"
+    id = galaxy_dataset.forward.id
+  }
+  element {
+    identifier = "reverse"
+    id = galaxy_dataset.reverse.id
+  }
+}
\ No newline at end of file
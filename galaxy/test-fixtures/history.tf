@@ -1,3 +1,4 @@
 resource "galaxy_history" "{{ .Name }}" {
   name = "{{ .HistoryName }}"
+  importable = true
 }
\ No newline at end of file
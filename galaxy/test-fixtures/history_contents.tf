@@ -0,0 +1,16 @@
+resource "galaxy_history" "test" {
+  name = "test"
+}
+
+resource "galaxy_dataset" "test" {
+  history_id = galaxy_history.test.id
+  content = "foo"
+  file_type = "txt"
+}
+
+data "galaxy_history_contents" "{{ .Name }}" {
+  depends_on = [galaxy_dataset.test]
+  history_id = galaxy_history.test.id
+  type = "dataset"
+  extension = "txt"
+}
\ No newline at end of file
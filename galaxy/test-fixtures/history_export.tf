@@ -0,0 +1,20 @@
+resource "galaxy_history" "test" {
+  name = "test"
+}
+
+resource "galaxy_dataset" "test" {
+  history_id = galaxy_history.test.id
+  content = "foo"
+  file_type = "txt"
+}
+
+resource "galaxy_history_export" "{{ .Name }}" {
+  depends_on = [galaxy_dataset.test]
+  history_id = galaxy_history.test.id
+  path = "{{ .Path }}"
+}
+
+resource "galaxy_history" "imported" {
+  name = "imported"
+  source_archive = galaxy_history_export.{{ .Name }}.path
+}
\ No newline at end of file
@@ -0,0 +1,11 @@
+resource "galaxy_user" "test" {
+  username = "apikeytest"
+  email = "apikeytest@example.com"
+}
+
+resource "galaxy_user_api_key" "{{ .Name }}" {
+  user_id = galaxy_user.test.id
+  rotation_triggers = {
+    rotation = "{{ .Rotation }}"
+  }
+}
@@ -0,0 +1,6 @@
+resource "galaxy_user" "{{ .Name }}" {
+  username = "{{ .Username }}"
+  email = "{{ .Email }}"
+  active = {{ .Active }}
+  on_destroy = "delete"
+}
@@ -0,0 +1,12 @@
+resource "galaxy_user" "{{ .Name }}" {
+  username = "test-defaults"
+  email = "defaults@example.com"
+  manage_permissions = true
+  default_access_roles = [{{ range .Roles }}"{{ . }}", {{ end }}]
+  manage_extra_preferences = true
+  extra_preferences = {
+{{- range $field, $value := .ExtraPreferences }}
+    "{{ $field }}" = "{{ $value }}"
+{{- end }}
+  }
+}
@@ -0,0 +1,6 @@
+resource "galaxy_user" "{{ .Name }}" {
+  username = "test-disk-usage"
+  email = "disk-usage@example.com"
+  recalculate_disk_usage = true
+  purge_deleted_datasets = true
+}
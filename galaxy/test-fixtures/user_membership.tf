@@ -0,0 +1,6 @@
+resource "galaxy_user" "{{ .Name }}" {
+  username = "{{ .Username }}"
+  email = "{{ .Email }}"
+  manage_membership = true
+  groups = [{{ range .Groups }}"{{ . }}", {{ end }}]
+}
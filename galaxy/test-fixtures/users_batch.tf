@@ -0,0 +1,16 @@
+resource "galaxy_users_batch" "{{ .Name }}" {
+  concurrency = 2
+  on_destroy = "purge"
+{{- range .Users }}
+  user {
+    email = "{{ .Email }}"
+    username = "{{ .Username }}"
+{{- if .Password }}
+    password = "{{ .Password }}"
+{{- end }}
+{{- if .Expiry }}
+    expiry = "{{ .Expiry }}"
+{{- end }}
+  }
+{{- end }}
+}
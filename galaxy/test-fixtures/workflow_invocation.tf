@@ -0,0 +1,24 @@
+resource "galaxy_repository" "awkscript" {
+  tool_shed = "toolshed.g2.bx.psu.edu"
+  owner = "brinkmanlab"
+  name = "awkscript"
+  changeset_revision = "7966a43dbc9e"
+  remove_from_disk = true
+}
+
+resource "galaxy_stored_workflow" "test" {
+  depends_on = [galaxy_repository.awkscript]
+  json = <<EOF
+{{ .Json -}}
+EOF
+}
+
+resource "galaxy_history" "test" {
+  name = "test"
+}
+
+resource "galaxy_workflow_invocation" "{{ .Name }}" {
+  workflow_id = galaxy_stored_workflow.test.id
+  history_id = galaxy_history.test.id
+  wait_for_completion = true
+}
\ No newline at end of file
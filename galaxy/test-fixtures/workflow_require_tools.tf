@@ -0,0 +1,7 @@
+resource "galaxy_stored_workflow" "{{ .Name }}" {
+  require_tools = {{ .RequireTools }}
+  check_tools   = true
+  json          = <<EOF
+{{ .Json -}}
+EOF
+}
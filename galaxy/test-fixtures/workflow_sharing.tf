@@ -0,0 +1,9 @@
+resource "galaxy_stored_workflow" "{{ .Name }}" {
+  published  = {{ .Published }}
+  importable = true
+  slug       = "{{ .Slug }}"
+  tags       = ["terraform"]
+  json       = <<EOF
+{{ .Json -}}
+EOF
+}
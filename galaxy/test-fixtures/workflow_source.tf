@@ -0,0 +1,6 @@
+resource "galaxy_stored_workflow" "{{ .Name }}" {
+  source {
+    trs_server = "{{ .TrsServer }}"
+    trs_id     = "{{ .TrsId }}"
+  }
+}
@@ -3,9 +3,12 @@ package galaxy
 import (
 	"crypto/sha1"
 	"encoding/base64"
+	"errors"
 	"github.com/brinkmanlab/blend4go"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
 	"reflect"
 	"strings"
 )
@@ -100,3 +103,35 @@ func HashString(value string) string {
 	h.Write([]byte(value))
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
+
+// Hash a password with a salted, slow hash so that the password can not be recovered from state
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Check if a password stored in state as a hash matches a configured password
+func passwordMatches(hash, password string) bool {
+	return hash != "" && password != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Galaxy versions without an endpoint respond with 404, or with the HTML of the client for legacy controllers
+func endpointUnsupported(status int, contentType string) bool {
+	switch status {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return status < 300 && contentType != "" && !strings.Contains(contentType, "json")
+}
+
+// Returned when the Galaxy instance does not provide an endpoint
+var errEndpointUnsupported = errors.New("endpoint not supported by Galaxy")
+
+// Warn that attributes are left unknown as the Galaxy instance does not provide an endpoint
+func unsupportedWarning(endpoint string, attributes ...string) diag.Diagnostics {
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "Galaxy does not support " + endpoint,
+		Detail:   "Unable to read " + strings.Join(attributes, ", ") + ". Upgrade Galaxy to manage these attributes.",
+	}}
+}